@@ -0,0 +1,376 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sp41414/chess/internal/book"
+	"github.com/sp41414/chess/internal/engine"
+)
+
+// commands are run in the terminal instead of opening the window when the
+// binary is started with their name as the first argument, e.g.
+// "chess mate -n 2 <fen>".
+var commands = map[string]func(args []string) error{
+	"book":  runBook,
+	"mate":  runMate,
+	"solve": runSolve,
+	"tb":    runTablebase,
+}
+
+// runCommand runs the command named by the first argument and returns false
+// if there is no such command.
+func runCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		return false, nil
+	}
+
+	return true, command(args[1:])
+}
+
+// parseFEN returns a board for the FEN, which may be split across several
+// arguments since it contains spaces. Positions the engine cannot search,
+// such as one without a king, are rejected.
+func parseFEN(args []string) (*engine.Board, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing FEN")
+	}
+
+	engine.Init("")
+	b := engine.InitBoard("")
+	if err := b.ParseFEN(strings.Join(args, " ")); err != nil {
+		return nil, err
+	}
+	if err := b.ValidateKings(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func runMate(args []string) error {
+	fs := flag.NewFlagSet("mate", flag.ContinueOnError)
+	n := fs.Int("n", 2, "maximum number of moves to mate in")
+	checks := fs.Bool("checks", false, "only consider checking moves for the attacking side")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chess mate [-n moves] [-checks] <fen>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	b, err := parseFEN(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	solution := b.SolveMate(*n, *checks)
+	if solution == nil {
+		fmt.Printf("No forced mate in %d\n", *n)
+		return nil
+	}
+
+	fmt.Printf("Mate in %d\n", solution.Moves)
+	writeMateTree(os.Stdout, solution.Key, b.FullMove, b.SideToMove, "")
+
+	return nil
+}
+
+// moveLabel returns the move number prefix for a move by side, "1." for
+// white and "1..." for black.
+func moveLabel(number, side int) string {
+	if side == engine.White {
+		return fmt.Sprintf("%d. ", number)
+	}
+	return fmt.Sprintf("%d... ", number)
+}
+
+// nextMove returns the move number and side after a move by side.
+func nextMove(number, side int) (int, int) {
+	if side == engine.Black {
+		number++
+	}
+	return number, side ^ 1
+}
+
+// writeMateTree writes an attacking move on its own line followed by every
+// defence, indented, with the attacking side's answer on the same line.
+func writeMateTree(w io.Writer, node *engine.MateNode, number, side int, indent string) {
+	fmt.Fprintf(w, "%s%s%s\n", indent, moveLabel(number, side), node.SAN)
+
+	defenceNumber, defender := nextMove(number, side)
+	answerNumber, _ := nextMove(defenceNumber, defender)
+	for _, defence := range node.Replies {
+		line := indent + "    " + moveLabel(defenceNumber, defender) + defence.SAN
+		if len(defence.Replies) == 0 {
+			fmt.Fprintln(w, line)
+			continue
+		}
+
+		answer := defence.Replies[0]
+		if len(answer.Replies) == 0 {
+			fmt.Fprintf(w, "%s %s%s\n", line, moveLabel(answerNumber, side), answer.SAN)
+			continue
+		}
+
+		fmt.Fprintln(w, line)
+		writeMateTree(w, answer, answerNumber, side, indent+"        ")
+	}
+}
+
+func runSolve(args []string) error {
+	fs := flag.NewFlagSet("solve", flag.ContinueOnError)
+	stipulation := fs.String("s", "h#2", "stipulation: #n, h#n, s#n, =n or h=n")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chess solve [-s stipulation] <fen>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	st, err := engine.ParseStipulation(*stipulation)
+	if err != nil {
+		return err
+	}
+
+	b, err := parseFEN(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	result := b.SolveProblem(st)
+	if len(result.Solutions) == 0 {
+		fmt.Printf("%s: no solution\n", result.Stipulation)
+		return nil
+	}
+
+	fmt.Println(result.Stipulation)
+	for _, line := range result.Notation {
+		fmt.Println(line)
+	}
+	if result.Cooked {
+		fmt.Println("Cooked")
+	}
+	for _, dual := range result.Duals {
+		fmt.Println("Dual:", dual)
+	}
+
+	return nil
+}
+
+// tbCommands are the subcommands of "chess tb".
+var tbCommands = map[string]func(args []string) error{
+	"gen":   runTablebaseGen,
+	"probe": runTablebaseProbe,
+}
+
+func runTablebase(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: chess tb gen|probe ...")
+	}
+
+	command, ok := tbCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown tb command %q, expected gen or probe", args[0])
+	}
+
+	return command(args[1:])
+}
+
+// parseInterspersed parses flags that may appear before or after the
+// positional arguments, e.g. "KQK -o dir", and returns the positionals.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func runTablebaseGen(args []string) error {
+	defaultDir, err := tablebaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("tb gen", flag.ContinueOnError)
+	out := fs.String("o", defaultDir, "directory to write the tables to")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chess tb gen <material> [-o dir]")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one material such as KQK or KBNK")
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+
+	engine.Init("")
+	tables := engine.Tablebases{}
+	if err := tables.LoadDir(*out); err != nil {
+		return err
+	}
+
+	t, err := tables.Generate(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := tables.SaveDir(*out); err != nil {
+		return err
+	}
+
+	fmt.Printf("Generated %s in %s\n", t.Material, *out)
+	return nil
+}
+
+func runTablebaseProbe(args []string) error {
+	defaultDir, err := tablebaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("tb probe", flag.ContinueOnError)
+	dir := fs.String("d", defaultDir, "directory to load the tables from")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chess tb probe [-d dir] <fen>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	b, err := parseFEN(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	tables := engine.Tablebases{}
+	if err := tables.LoadDir(*dir); err != nil {
+		return err
+	}
+
+	result, ok := tables.Probe(b)
+	if !ok {
+		return errNoTablebase
+	}
+
+	switch result.Outcome {
+	case engine.TBWin:
+		fmt.Printf("Win, mate in %d plies\n", result.DTM)
+	case engine.TBLoss:
+		fmt.Printf("Loss, mated in %d plies\n", result.DTM)
+	default:
+		fmt.Println("Draw")
+	}
+	if m, _, ok := tables.BestMove(b); ok {
+		fmt.Println("Best move:", b.SAN(m))
+	}
+
+	return nil
+}
+
+// bookCommands are the subcommands of "chess book".
+var bookCommands = map[string]func(args []string) error{
+	"build": runBookBuild,
+}
+
+func runBook(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: chess book build ...")
+	}
+
+	command, ok := bookCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown book command %q, expected build", args[0])
+	}
+
+	return command(args[1:])
+}
+
+func runBookBuild(args []string) error {
+	defaultPath, err := bookPath()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("book build", flag.ContinueOnError)
+	out := fs.String("o", defaultPath, "file to write the book to")
+	plies := fs.Int("plies", 20, "plies from the start of each game to include, 0 for all")
+	minGames := fs.Int("min-games", 1, "games a move must have been played in")
+	minElo := fs.Int("min-elo", 0, "rating the side making a move must have, 0 to include unrated players")
+	minScore := fs.Float64("min-score", 0, "share of the points, 0 to 1, a move must have scored")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chess book build [-o file] [-plies n] [-min-games n] [-min-elo n] [-min-score x] <pgn>...")
+		fs.PrintDefaults()
+	}
+	paths, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected at least one PGN file")
+	}
+
+	engine.Init("")
+	builder := book.NewBuilder(book.Options{
+		MaxPly:   *plies,
+		MinGames: *minGames,
+		MinElo:   *minElo,
+		MinScore: *minScore,
+	})
+
+	skipped := 0
+	for _, path := range paths {
+		n := 0
+		err := book.LoadPGN(path, func(g *book.Game) error {
+			n++
+			if err := builder.Add(g); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: skipping game %d: %v\n", path, n, err)
+				skipped++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		return err
+	}
+	bk := builder.Book()
+	if err := bk.Save(*out); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d entries from %d games to %s\n", bk.Len(), builder.Games, *out)
+	if skipped > 0 {
+		fmt.Printf("Skipped %d games that could not be replayed\n", skipped)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Package book builds Polyglot opening books from PGN game collections.
+package book
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/sp41414/chess/internal/engine"
+)
+
+// Options choose the games and moves that go into a book.
+type Options struct {
+	// Plies from the start of each game to count, 0 for the whole game
+	MaxPly int
+	// Games a move must have been played in to be kept
+	MinGames int
+	// Rating the side making a move must have for it to be counted, 0 to
+	// count unrated players too
+	MinElo int
+	// Share of the points, 0 to 1, the side making a move must have scored
+	// with it for it to be kept
+	MinScore float64
+}
+
+// Builder counts how often each move was played in each position and how
+// it scored, then writes the moves that pass the filters as a book.
+type Builder struct {
+	opts  Options
+	stats map[bookMove]*moveStats
+	// Games counted so far
+	Games int
+}
+
+// bookMove is a move in the position with the given Polyglot key.
+type bookMove struct {
+	key  uint64
+	move engine.Move
+}
+
+// moveStats are the results of the games a move was played in, from the
+// point of view of the side that played it.
+type moveStats struct {
+	games, wins, draws int
+}
+
+// NewBuilder returns an empty builder.
+func NewBuilder(opts Options) *Builder {
+	return &Builder{
+		opts:  opts,
+		stats: map[bookMove]*moveStats{},
+	}
+}
+
+// Add replays the game and counts its moves up to MaxPly. Games from a FEN
+// tag start from that position. Games without a result are skipped, and an
+// error is returned for a game with an illegal move within MaxPly or an
+// invalid position, in which case none of its moves are counted. Needs the
+// engine lookup tables to be initialized.
+func (bd *Builder) Add(g *Game) error {
+	// Points scored by white, in half points
+	var white int
+	switch g.Result {
+	case "1-0":
+		white = 2
+	case "1/2-1/2":
+		white = 1
+	case "0-1":
+		white = 0
+	default:
+		return nil
+	}
+
+	b := engine.InitBoard("")
+	fen := g.Tags["FEN"]
+	if fen == "" {
+		fen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	}
+	if err := b.ParseFEN(fen); err != nil {
+		return err
+	}
+	if err := b.ValidateKings(); err != nil {
+		return err
+	}
+
+	rated := [2]bool{bd.rated(g.Tags["WhiteElo"]), bd.rated(g.Tags["BlackElo"])}
+	// Moves are only counted once every move up to MaxPly is known to be
+	// legal
+	type played struct {
+		move bookMove
+		side int
+	}
+	var seen []played
+	for ply, san := range g.Moves {
+		if bd.opts.MaxPly > 0 && ply >= bd.opts.MaxPly {
+			break
+		}
+
+		m, err := b.ParseSAN(san)
+		if err != nil {
+			return fmt.Errorf("ply %d: %v", ply+1, err)
+		}
+		if rated[b.SideToMove] {
+			seen = append(seen, played{bookMove{engine.PolyglotKey(b), m}, b.SideToMove})
+		}
+		b.MakeMove(m)
+	}
+
+	for _, p := range seen {
+		s := bd.stats[p.move]
+		if s == nil {
+			s = &moveStats{}
+			bd.stats[p.move] = s
+		}
+
+		points := white
+		if p.side == engine.Black {
+			points = 2 - white
+		}
+
+		s.games++
+		switch points {
+		case 2:
+			s.wins++
+		case 1:
+			s.draws++
+		}
+	}
+
+	bd.Games++
+	return nil
+}
+
+// rated reports whether a player with the Elo tag value passes MinElo.
+func (bd *Builder) rated(elo string) bool {
+	if bd.opts.MinElo <= 0 {
+		return true
+	}
+	rating, err := strconv.Atoi(elo)
+	return err == nil && rating >= bd.opts.MinElo
+}
+
+// Book returns the moves that pass MinGames and MinScore as a Polyglot
+// book. Weights are twice the wins plus the draws, scaled down per position
+// if needed to fit the 16-bit weight field.
+func (bd *Builder) Book() *engine.Book {
+	var entries []engine.BookEntry
+	best := map[uint64]int{}
+	for bm, s := range bd.stats {
+		score := float64(2*s.wins+s.draws) / float64(2*s.games)
+		if s.games < bd.opts.MinGames || score < bd.opts.MinScore {
+			continue
+		}
+		entries = append(entries, engine.BookEntry{Key: bm.key, Move: bm.move})
+		best[bm.key] = max(best[bm.key], 2*s.wins+s.draws)
+	}
+
+	for i := range entries {
+		s := bd.stats[bookMove{entries[i].Key, entries[i].Move}]
+		weight := 2*s.wins + s.draws
+		if top := best[entries[i].Key]; top > math.MaxUint16 {
+			weight = weight * math.MaxUint16 / top
+		}
+		entries[i].Weight = uint16(weight)
+	}
+
+	return engine.NewBook(entries)
+}
@@ -0,0 +1,138 @@
+package book
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sp41414/chess/internal/engine"
+)
+
+var testGames = []*Game{
+	{Tags: map[string]string{"WhiteElo": "2000", "BlackElo": "1500"}, Moves: []string{"e4", "e5", "Nf3"}, Result: "1-0"},
+	{Tags: map[string]string{"WhiteElo": "2100", "BlackElo": "2200"}, Moves: []string{"e4", "c5"}, Result: "0-1"},
+	{Tags: map[string]string{}, Moves: []string{"d4", "d5"}, Result: "1/2-1/2"},
+	// Unfinished, so not counted
+	{Tags: map[string]string{}, Moves: []string{"e4", "e5"}, Result: "*"},
+}
+
+// bookMoves returns the book moves after the moves in SAN from the
+// starting position as "SAN:weight", highest weight first.
+func bookMoves(t *testing.T, bk *engine.Book, moves ...string) string {
+	b := engine.InitBoard("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	for _, san := range moves {
+		m, err := b.ParseSAN(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.MakeMove(m)
+	}
+
+	var got []string
+	for _, bm := range bk.Moves(b) {
+		got = append(got, b.SAN(bm.Move)+":"+strconv.Itoa(bm.Weight))
+	}
+	return strings.Join(got, " ")
+}
+
+func TestBuilder(t *testing.T) {
+	engine.Init("")
+
+	tests := []struct {
+		name    string
+		opts    Options
+		start   string
+		afterE4 string
+	}{
+		// Weights are twice the wins plus the draws for the side moving
+		{"all moves", Options{}, "e4:2 d4:1", "c5:2 e5:0"},
+		{"min games", Options{MinGames: 2}, "e4:2", ""},
+		{"min elo", Options{MinElo: 2000}, "e4:2", "c5:2"},
+		{"min score", Options{MinScore: 0.5}, "e4:2 d4:1", "c5:2"},
+		{"max ply", Options{MaxPly: 1}, "e4:2 d4:1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.opts)
+			for _, g := range testGames {
+				if err := builder.Add(g); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if builder.Games != 3 {
+				t.Errorf("Games = %d, want 3", builder.Games)
+			}
+
+			bk := builder.Book()
+			if got := bookMoves(t, bk); got != tt.start {
+				t.Errorf("start position: %q, want %q", got, tt.start)
+			}
+			if got := bookMoves(t, bk, "e4"); got != tt.afterE4 {
+				t.Errorf("after 1.e4: %q, want %q", got, tt.afterE4)
+			}
+		})
+	}
+}
+
+func TestBuilderTranspositions(t *testing.T) {
+	engine.Init("")
+	builder := NewBuilder(Options{})
+	for _, g := range []*Game{
+		{Moves: []string{"d4", "d5", "Nf3", "Nf6", "c4"}, Result: "1-0"},
+		{Moves: []string{"Nf3", "d5", "d4", "Nf6", "Bf4"}, Result: "1/2-1/2"},
+	} {
+		if err := builder.Add(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := bookMoves(t, builder.Book(), "d4", "d5", "Nf3", "Nf6"); got != "c4:2 Bf4:1" {
+		t.Errorf("after the transposition: %q, want c4:2 Bf4:1", got)
+	}
+}
+
+func TestBuilderRejectsIllegalGames(t *testing.T) {
+	engine.Init("")
+	builder := NewBuilder(Options{})
+
+	for _, g := range []*Game{
+		{Moves: []string{"e4", "e4"}, Result: "1-0"},
+		{Tags: map[string]string{"FEN": "8/8/8/8/8/8/8/8 w - - 0 1"}, Result: "1-0"},
+	} {
+		if err := builder.Add(g); err == nil {
+			t.Errorf("Add(%v) succeeded, want an error", g)
+		}
+	}
+	if builder.Games != 0 || builder.Book().Len() != 0 {
+		t.Errorf("illegal games were counted: %d games, %d entries", builder.Games, builder.Book().Len())
+	}
+}
+
+func TestBuildFromPGN(t *testing.T) {
+	engine.Init("")
+	builder := NewBuilder(Options{MaxPly: 4})
+	if err := ReadPGN(strings.NewReader(testPGN), builder.Add); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "book.bin")
+	if err := builder.Book().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	bk, err := engine.LoadBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first game has a result, and only its first 4 plies count
+	if bk.Len() != 4 {
+		t.Errorf("Len = %d, want 4", bk.Len())
+	}
+	if got := bookMoves(t, bk, "e4", "e5", "f4"); got != "exf4:0" {
+		t.Errorf("after 2.f4: %q, want exf4:0", got)
+	}
+	if got := bookMoves(t, bk, "e4", "e5", "f4", "exf4"); got != "" {
+		t.Errorf("after 2...exf4: %q, want nothing past ply 4", got)
+	}
+}
@@ -0,0 +1,172 @@
+package book
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// Game is a game read from a PGN file.
+type Game struct {
+	// Tag pairs such as "White", "WhiteElo" or "FEN"
+	Tags map[string]string
+	// Main line moves in SAN, without move numbers, comments, NAGs and
+	// variations
+	Moves []string
+	// "1-0", "0-1", "1/2-1/2" or "*"
+	Result string
+}
+
+// LoadPGN calls fn for every game in the PGN file at path, see ReadPGN.
+func LoadPGN(path string, fn func(g *Game) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return ReadPGN(f, fn)
+}
+
+// ReadPGN calls fn for every game in r in order, stopping at the first error
+// fn returns. Games are read one at a time so large archives are not held in
+// memory. Moves are not checked here, as that needs the position.
+func ReadPGN(r io.Reader, fn func(g *Game) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	tags := map[string]string{}
+	movetext := strings.Builder{}
+	inComment := false
+	flush := func() error {
+		if len(tags) == 0 && movetext.Len() == 0 {
+			return nil
+		}
+
+		g := &Game{Tags: tags}
+		g.Moves, g.Result = parseMovetext(movetext.String())
+		if g.Result == "" {
+			g.Result = tags["Result"]
+		}
+
+		tags = map[string]string{}
+		movetext.Reset()
+		return fn(g)
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Lines starting with % are escaped, e.g. for comments of the tool
+		// that wrote the file
+		if strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		if !inComment && strings.HasPrefix(line, "[") {
+			// A tag after the moves starts the next game
+			if movetext.Len() > 0 {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if key, value, ok := parseTag(line); ok {
+				tags[key] = value
+			}
+			continue
+		}
+
+		movetext.WriteString(line)
+		movetext.WriteByte('\n')
+		for _, c := range line {
+			switch c {
+			case '{':
+				inComment = true
+			case '}':
+				inComment = false
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+// parseTag parses a tag pair line such as `[White "Carlsen, Magnus"]`.
+func parseTag(line string) (string, string, bool) {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+	key, value, ok := strings.Cut(line, " ")
+	if !ok {
+		return "", "", false
+	}
+
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+	return key, strings.ReplaceAll(value, `\"`, `"`), true
+}
+
+// parseMovetext returns the main line moves and the result of a game's
+// movetext, skipping move numbers, {} and ; comments, NAGs and variations.
+func parseMovetext(text string) ([]string, string) {
+	var moves []string
+	result := ""
+	depth := 0
+
+	for i := 0; i < len(text); {
+		switch c := text[i]; c {
+		case '{', ';':
+			end := byte('}')
+			if c == ';' {
+				end = '\n'
+			}
+			j := strings.IndexByte(text[i:], end)
+			if j == -1 {
+				return moves, result
+			}
+			i += j + 1
+			continue
+		case '(':
+			depth++
+			i++
+			continue
+		case ')':
+			depth = max(depth-1, 0)
+			i++
+			continue
+		case ' ', '\t', '\r', '\n':
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(text) && !strings.ContainsRune(" \t\r\n{};()", rune(text[j])) {
+			j++
+		}
+		token := text[i:j]
+		i = j
+		if depth > 0 {
+			continue
+		}
+
+		switch token {
+		case "1-0", "0-1", "1/2-1/2", "*":
+			result = token
+			continue
+		}
+
+		// Move numbers, "12." or "12...", may be written against the move
+		if k := strings.IndexFunc(token, func(r rune) bool { return r < '0' || r > '9' }); k > 0 && token[k] == '.' {
+			token = strings.TrimLeft(token[k:], ".")
+		}
+		token = strings.TrimLeft(token, ".")
+		if token == "" || strings.ContainsAny(token[:1], "$!?") {
+			continue
+		}
+
+		moves = append(moves, token)
+	}
+
+	return moves, result
+}
@@ -0,0 +1,79 @@
+package book
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testPGN = `% Written by hand
+[Event "Casual"]
+[White "Anderssen, Adolf"]
+[Black "Kieseritzky, Lionel"]
+[WhiteElo "2600"]
+[Result "1-0"]
+
+1. e4 e5 2. f4 {King's Gambit, with a comment
+spanning lines [like this]} exf4 3.Bc4 $1 Qh4+ (3... d5 4. Bxd5 (4. exd5)) 4. Kf1
+; rest of line comment
+b5!? 1-0
+
+[Event "Second"]
+[FEN "4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1"]
+[Result "1/2-1/2"]
+
+1. O-O Kd7 *
+`
+
+func TestReadPGN(t *testing.T) {
+	var games []*Game
+	err := ReadPGN(strings.NewReader(testPGN), func(g *Game) error {
+		games = append(games, g)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("read %d games, want 2", len(games))
+	}
+
+	first := games[0]
+	if first.Tags["White"] != "Anderssen, Adolf" || first.Tags["WhiteElo"] != "2600" {
+		t.Errorf("tags = %v", first.Tags)
+	}
+	if want := []string{"e4", "e5", "f4", "exf4", "Bc4", "Qh4+", "Kf1", "b5!?"}; !slices.Equal(first.Moves, want) {
+		t.Errorf("moves = %q, want %q", first.Moves, want)
+	}
+	if first.Result != "1-0" {
+		t.Errorf("result = %q, want 1-0", first.Result)
+	}
+
+	// The movetext result wins over the tag
+	second := games[1]
+	if !slices.Equal(second.Moves, []string{"O-O", "Kd7"}) || second.Result != "*" {
+		t.Errorf("second game = %q %q", second.Moves, second.Result)
+	}
+	if second.Tags["FEN"] != "4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1" {
+		t.Errorf("FEN tag = %q", second.Tags["FEN"])
+	}
+}
+
+func TestParseMovetext(t *testing.T) {
+	tests := []struct {
+		text   string
+		moves  string
+		result string
+	}{
+		{"1.e4 e5 2.Nf3", "e4 e5 Nf3", ""},
+		{"12... Nf6 13. O-O 0-0-0 0-1", "Nf6 O-O 0-0-0", "0-1"},
+		{"1. d4 $14 {good} (1. e4 (1. c4)) d5 1/2-1/2", "d4 d5", "1/2-1/2"},
+		{"1. e4 {unterminated", "e4", ""},
+	}
+	for _, tt := range tests {
+		moves, result := parseMovetext(tt.text)
+		if strings.Join(moves, " ") != tt.moves || result != tt.result {
+			t.Errorf("parseMovetext(%q) = %q, %q, want %q, %q", tt.text, moves, result, tt.moves, tt.result)
+		}
+	}
+}
@@ -8,6 +8,8 @@ import "math/bits"
 func Init(fen string) *Board {
 	InitLookupTables()
 	InitMagic()
+	InitKPK()
+	InitZobrist()
 	return InitBoard(fen)
 }
 
@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Explain describes the legal move in plain English for beginners, built
+// from what the move does on the board: material won or lost by static
+// exchange evaluation, checks, the motifs it creates for either side and
+// the threats against the moving side it deals with. There is no engine
+// evaluation, so the move is not scored and no better move is suggested.
+// The same move in the same position always gets the same text.
+func (b *Board) Explain(m Move) string {
+	color := b.SideToMove
+	sentences := []string{b.action(m)}
+
+	before := b.Threats()
+	occupancy := b.Occupancy[All]
+
+	undo := b.makeMove(m)
+	after := b.Threats()
+	sentences = append(sentences, b.checkSentence(m.To(), occupancy)...)
+
+	// Nothing else matters once the game is over
+	if len(b.GenerateMoves()) == 0 {
+		b.unmakeMove(m, undo)
+		return strings.Join(sentences, " ")
+	}
+
+	// Pieces already named by a pin, skewer or fork are not also called
+	// undefended, and the exchange evaluation covers a capturing piece
+	created := motifDiff(after, before)
+	var named []int
+	for _, motif := range created {
+		if motif.Color == color && motif.Kind != Hanging {
+			named = append(named, motif.Squares[1:]...)
+		}
+	}
+	if m.IsCapture() {
+		named = append(named, m.To())
+	}
+
+	var weaknesses []string
+	for _, motif := range created {
+		if motif.Kind == Hanging && slices.Contains(named, motif.Squares[0]) {
+			continue
+		}
+		if motif.Color == color {
+			sentences = append(sentences, b.gainSentence(motif)...)
+		} else {
+			weaknesses = append(weaknesses, b.weaknessSentence(motif)...)
+		}
+	}
+	for _, motif := range b.discoveries(m.To(), occupancy) {
+		if motif.Kind == DiscoveredAttack {
+			sentences = append(sentences, fmt.Sprintf("It uncovers an attack by %s on %s.",
+				b.describe(motif.Squares[1]), b.describe(motif.Squares[2])))
+		}
+	}
+
+	// A piece moved to a square where it can be won, captures are covered
+	// by the exchange evaluation
+	if !m.IsCapture() && b.pieceAt(m.To())%6 != WhiteKing && b.defenders(m.To(), color) != 0 && b.exposed(m.To()) {
+		_, attacker := b.leastValuableAttacker(b.defenders(m.To(), color^1), color^1)
+		weaknesses = append(weaknesses, fmt.Sprintf("However, %s can be taken by a %s.",
+			b.describe(m.To()), pieceNames[attacker%6]))
+	}
+	b.unmakeMove(m, undo)
+
+	for _, motif := range motifDiff(before, after) {
+		if motif.Color != color {
+			sentences = append(sentences, b.parrySentence(motif)...)
+		}
+	}
+
+	return strings.Join(append(sentences, weaknesses...), " ")
+}
+
+// describe names the piece on sq, e.g. "the knight on f3".
+func (b *Board) describe(sq int) string {
+	piece := b.pieceAt(sq)
+	if piece%6 == WhiteKing {
+		return "the " + colorNames[piece/6] + " king"
+	}
+	return "the " + pieceNames[piece%6] + " on " + squareName(sq)
+}
+
+// describeAll joins the names of the pieces on the squares into a list,
+// e.g. "the king and the rook on a8".
+func (b *Board) describeAll(sqs []int) string {
+	names := make([]string, len(sqs))
+	for i, sq := range sqs {
+		names[i] = b.describe(sq)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// capitalize returns the text with its first letter in upper case.
+func capitalize(text string) string {
+	return strings.ToUpper(text[:1]) + text[1:]
+}
+
+// action is the opening sentence saying what the move does, with the
+// material balance of any capture after the exchanges on its square.
+func (b *Board) action(m Move) string {
+	san := b.SAN(m)
+	from, to, flags := m.From(), m.To(), m.Flags()
+
+	switch flags {
+	case KCastle:
+		return san + " castles kingside, tucking the king away and bringing the rook towards the centre."
+	case QCastle:
+		return san + " castles queenside, tucking the king away and bringing the rook towards the centre."
+	}
+
+	text := fmt.Sprintf("%s moves %s to %s", san, b.describe(from), squareName(to))
+	switch {
+	case flags == EPCapture:
+		captured := to - 8
+		if b.SideToMove == Black {
+			captured = to + 8
+		}
+		text = fmt.Sprintf("%s captures the pawn on %s en passant", san, squareName(captured))
+	case m.IsCapture():
+		text = fmt.Sprintf("%s captures %s", san, b.describe(to))
+	}
+
+	if m.IsPromotion() {
+		text += " and promotes to a " + pieceNames[WhiteKnight+flags&0x3]
+	}
+
+	if m.IsCapture() {
+		switch see := b.SEE(m); {
+		case see > 0:
+			text += fmt.Sprintf(", winning material (%+.1f)", float64(see)/100)
+		case see == 0:
+			text += ", an even trade"
+		default:
+			text += fmt.Sprintf(", but loses material after the recaptures (%+.1f)", float64(see)/100)
+		}
+	}
+
+	return text + "."
+}
+
+// checkSentence describes checks, mate and stalemate given by a move to sq
+// from a position with the given occupancy. The move has been made.
+func (b *Board) checkSentence(sq int, before Bitboard) []string {
+	if len(b.GenerateMoves()) == 0 {
+		if b.IsInCheck() {
+			return []string{"It is checkmate."}
+		}
+		return []string{"It is stalemate, so the game is drawn."}
+	}
+
+	checkers := b.checkers(b.SideToMove)
+	switch {
+	case checkers == 0:
+		return nil
+	case checkers.Count() > 1:
+		return []string{"It gives double check, so the king has to move."}
+	}
+
+	if checker := checkers.LSB(); checker != sq {
+		for _, motif := range b.discoveries(sq, before) {
+			if motif.Kind == DiscoveredCheck {
+				return []string{fmt.Sprintf("It uncovers a check from %s.", b.describe(checker))}
+			}
+		}
+	}
+	return []string{"It gives check."}
+}
+
+// gainSentence describes a motif the move creates for the moving side. The
+// move has been made.
+func (b *Board) gainSentence(motif Motif) []string {
+	sqs := motif.Squares
+	switch motif.Kind {
+	case Hanging:
+		return []string{fmt.Sprintf("It attacks %s, which is undefended.", b.describe(sqs[0]))}
+	case AbsolutePin:
+		return []string{fmt.Sprintf("%s pins %s to the king.", capitalize(b.describe(sqs[0])), b.describe(sqs[1]))}
+	case RelativePin:
+		return []string{fmt.Sprintf("%s pins %s to %s.", capitalize(b.describe(sqs[0])), b.describe(sqs[1]), b.describe(sqs[2]))}
+	case Skewer:
+		return []string{fmt.Sprintf("%s skewers %s and %s.", capitalize(b.describe(sqs[0])), b.describe(sqs[1]), b.describe(sqs[2]))}
+	case Fork:
+		return []string{fmt.Sprintf("%s forks %s.", capitalize(b.describe(sqs[0])), b.describeAll(sqs[1:]))}
+	case OverloadedDefender:
+		return []string{fmt.Sprintf("%s is overloaded, as the only defender of %s.", capitalize(b.describe(sqs[0])), b.describeAll(sqs[1:]))}
+	case BackRankWeakness:
+		return []string{fmt.Sprintf("%s is stuck on its back rank, watch for a back-rank mate.", capitalize(b.describe(sqs[0])))}
+	}
+	return nil
+}
+
+// weaknessSentence describes a motif the move allows against the moving
+// side. The move has been made.
+func (b *Board) weaknessSentence(motif Motif) []string {
+	sqs := motif.Squares
+	switch motif.Kind {
+	case Hanging:
+		return []string{fmt.Sprintf("However, it leaves %s undefended.", b.describe(sqs[0]))}
+	case AbsolutePin:
+		return []string{fmt.Sprintf("However, %s is now pinned to the king.", b.describe(sqs[1]))}
+	case RelativePin:
+		return []string{fmt.Sprintf("However, %s is now pinned to %s.", b.describe(sqs[1]), b.describe(sqs[2]))}
+	case Skewer:
+		return []string{fmt.Sprintf("However, %s and %s can now be skewered.", b.describe(sqs[1]), b.describe(sqs[2]))}
+	case Fork:
+		return []string{fmt.Sprintf("However, %s now forks %s.", b.describe(sqs[0]), b.describeAll(sqs[1:]))}
+	case OverloadedDefender:
+		return []string{fmt.Sprintf("However, %s is now overloaded, as the only defender of %s.", b.describe(sqs[0]), b.describeAll(sqs[1:]))}
+	case BackRankWeakness:
+		return []string{"However, it leaves the king weak on its back rank."}
+	}
+	return nil
+}
+
+// parrySentence describes a threat against the moving side that the move
+// deals with. The move has not been made yet.
+func (b *Board) parrySentence(motif Motif) []string {
+	sqs := motif.Squares
+	switch motif.Kind {
+	case Hanging:
+		return []string{fmt.Sprintf("It deals with the threat to %s.", b.describe(sqs[0]))}
+	case AbsolutePin, RelativePin:
+		return []string{fmt.Sprintf("It gets out of the pin on %s.", b.describe(sqs[1]))}
+	case Skewer:
+		return []string{fmt.Sprintf("It gets out of the skewer by %s.", b.describe(sqs[0]))}
+	case Fork:
+		return []string{fmt.Sprintf("It gets out of the fork by %s.", b.describe(sqs[0]))}
+	case OverloadedDefender:
+		return []string{fmt.Sprintf("It takes the load off %s.", b.describe(sqs[0]))}
+	case BackRankWeakness:
+		return []string{"It removes the back-rank weakness."}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package engine
+
+import "testing"
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string
+		want string
+	}{
+		{
+			"winning capture",
+			"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5",
+			"Rxe5 captures the pawn on e5, winning material (+1.0). However, it leaves the king weak on its back rank.",
+		},
+		{
+			"losing capture",
+			"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "f3h3",
+			"Qxh3 captures the pawn on h3, but loses material after the recaptures (-3.0).",
+		},
+		{
+			"fork",
+			"r3k3/8/8/3N4/8/8/8/4K3 w - - 0 1", "d5c7",
+			"Nc7+ moves the knight on d5 to c7. It gives check. The knight on c7 forks the rook on a8 and the black king.",
+		},
+		{
+			"pin",
+			"4k3/8/2n5/8/8/8/8/K4B2 w - - 0 1", "f1b5",
+			"Bb5 moves the bishop on f1 to b5. The bishop on b5 pins the knight on c6 to the king.",
+		},
+		{
+			"skewer",
+			"4q3/8/8/4k3/8/8/8/K6R w - - 0 1", "h1e1",
+			"Re1+ moves the rook on h1 to e1. It gives check. The rook on e1 skewers the black king and the queen on e8.",
+		},
+		{
+			"discovered check",
+			"4k3/8/8/8/4N3/8/8/K3R3 w - - 0 1", "e4c3",
+			"Nc3+ moves the knight on e4 to c3. It uncovers a check from the rook on e1.",
+		},
+		{
+			"double check",
+			"4k3/8/8/8/4N3/8/8/K3R3 w - - 0 1", "e4d6",
+			"Nd6+ moves the knight on e4 to d6. It gives double check, so the king has to move.",
+		},
+		{
+			"checkmate",
+			"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8",
+			"Ra8# moves the rook on a1 to a8. It is checkmate.",
+		},
+		{
+			"stalemate",
+			"k7/8/8/1Q6/8/8/8/K7 w - - 0 1", "b5b6",
+			"Qb6 moves the queen on b5 to b6. It is stalemate, so the game is drawn.",
+		},
+		{
+			"castling",
+			"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1",
+			"O-O castles kingside, tucking the king away and bringing the rook towards the centre.",
+		},
+		{
+			"parried threat",
+			"4k3/8/8/8/r2N4/8/8/4K3 w - - 0 1", "d4f5",
+			"Nf5 moves the knight on d4 to f5. It deals with the threat to the knight on d4.",
+		},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := InitBoard(tt.fen)
+			m, err := b.ParseUCI(tt.move)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := b.Explain(m); got != tt.want {
+				t.Errorf("Explain(%s) =\n%q\nwant\n%q", tt.move, got, tt.want)
+			}
+			// The board is left as it was, so the text is the same again
+			if got := b.Explain(m); got != tt.want {
+				t.Errorf("second Explain(%s) =\n%q\nwant\n%q", tt.move, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package engine
+
+// The KPK bitbase stores one bit per king and pawn versus king position,
+// set if the side with the pawn wins. The pawn side is always normalized to
+// white with the pawn on files a-d, which leaves 24 pawn squares.
+const kpkSize = 2 * 24 * 64 * 64
+
+// KnownWinScore is the score given to positions known to be won, such as
+// a winning king and pawn versus king ending.
+const KnownWinScore = 10000
+
+// Results used while building the bitbase, combined as flags during
+// classification
+const (
+	kpkInvalid uint8 = 0
+	kpkUnknown uint8 = 1 << (iota - 1)
+	kpkDraw
+	kpkWin
+)
+
+var kpkBitbase [kpkSize / 32]uint32
+
+// kpkIndex packs the position into the bitbase index. The pawn rank is
+// stored counted down from the seventh rank.
+func kpkIndex(stm, bk, wk, psq int) int {
+	return wk | bk<<6 | stm<<12 | (psq%8)<<13 | (6-psq/8)<<15
+}
+
+// squareDistance returns the number of king moves between the squares.
+func squareDistance(a, b int) int {
+	return max(abs(a/8-b/8), abs(a%8-b%8))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// InitKPK builds the KPK bitbase by retrograde iteration: positions that are
+// immediately won, drawn or illegal are classified first, then every
+// unknown position is reclassified from its successors until nothing
+// changes. Remaining unknown positions are draws.
+func InitKPK() {
+	db := make([]uint8, kpkSize)
+	for idx := range kpkSize {
+		db[idx] = kpkInit(idx)
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for idx := range kpkSize {
+			if db[idx] == kpkUnknown {
+				db[idx] = kpkClassify(db, idx)
+				changed = changed || db[idx] != kpkUnknown
+			}
+		}
+	}
+
+	kpkBitbase = [kpkSize / 32]uint32{}
+	for idx := range kpkSize {
+		if db[idx] == kpkWin {
+			kpkBitbase[idx/32] |= 1 << (idx % 32)
+		}
+	}
+}
+
+// kpkSquares unpacks a bitbase index into the side to move and the squares
+// of the white king, black king and white pawn.
+func kpkSquares(idx int) (int, int, int, int) {
+	wk := idx & 0x3F
+	bk := (idx >> 6) & 0x3F
+	stm := (idx >> 12) & 1
+	psq := (6-(idx>>15)&0x7)*8 + (idx>>13)&0x3
+	return stm, wk, bk, psq
+}
+
+// kpkInit gives the result of positions decided without looking ahead.
+func kpkInit(idx int) uint8 {
+	stm, wk, bk, psq := kpkSquares(idx)
+
+	if squareDistance(wk, bk) <= 1 || wk == psq || bk == psq ||
+		(stm == White && WhitePawnMoves[psq].Occupied(bk)) {
+		return kpkInvalid
+	}
+
+	// The pawn promotes without being captured
+	if stm == White && psq/8 == 6 && wk != psq+8 &&
+		(squareDistance(bk, psq+8) > 1 || squareDistance(wk, psq+8) == 1) {
+		return kpkWin
+	}
+
+	// Stalemate, or the black king takes an undefended pawn
+	if stm == Black &&
+		(KingMoves[bk]&^(KingMoves[wk]|WhitePawnMoves[psq]) == 0 ||
+			(KingMoves[bk] &^ KingMoves[wk]).Occupied(psq)) {
+		return kpkDraw
+	}
+
+	return kpkUnknown
+}
+
+// kpkClassify combines the results of every move from the position. White
+// wins if any move wins, black draws if any move draws.
+func kpkClassify(db []uint8, idx int) uint8 {
+	stm, wk, bk, psq := kpkSquares(idx)
+
+	good, bad := kpkWin, kpkDraw
+	if stm == Black {
+		good, bad = kpkDraw, kpkWin
+	}
+
+	r := kpkInvalid
+	if stm == White {
+		moves := KingMoves[wk]
+		for moves != 0 {
+			r |= db[kpkIndex(Black, bk, moves.PopLSB(), psq)]
+		}
+
+		if psq/8 < 6 {
+			r |= db[kpkIndex(Black, bk, wk, psq+8)]
+		}
+		if psq/8 == 1 && psq+8 != wk && psq+8 != bk {
+			r |= db[kpkIndex(Black, bk, wk, psq+16)]
+		}
+	} else {
+		moves := KingMoves[bk]
+		for moves != 0 {
+			r |= db[kpkIndex(White, moves.PopLSB(), wk, psq)]
+		}
+	}
+
+	switch {
+	case r&good != 0:
+		return good
+	case r&kpkUnknown != 0:
+		return kpkUnknown
+	default:
+		return bad
+	}
+}
+
+// kpkProbe returns true if white wins with the normalized squares.
+func kpkProbe(stm, wk, bk, psq int) bool {
+	idx := kpkIndex(stm, bk, wk, psq)
+	return kpkBitbase[idx/32]&(1<<(idx%32)) != 0
+}
+
+// kpkNormalize returns the position as white king, black king and white
+// pawn squares on files a-d with white to move if the pawn side is to move.
+// ok is false if the board is not king and pawn versus king.
+func kpkNormalize(b *Board) (stm, wk, bk, psq int, ok bool) {
+	if b.Occupancy[All].Count() != 3 || (b.Pieces[WhitePawn]|b.Pieces[BlackPawn]).Count() != 1 {
+		return 0, 0, 0, 0, false
+	}
+
+	strong := White
+	if b.Pieces[BlackPawn] != 0 {
+		strong = Black
+	}
+
+	wk = b.Pieces[strong*6+5].LSB()
+	bk = b.Pieces[(strong^1)*6+5].LSB()
+	psq = b.Pieces[strong*6].LSB()
+	stm = White
+	if b.SideToMove != strong {
+		stm = Black
+	}
+
+	if strong == Black {
+		wk, bk, psq = wk^56, bk^56, psq^56
+	}
+	if psq%8 > 3 {
+		wk, bk, psq = wk^7, bk^7, psq^7
+	}
+
+	return stm, wk, bk, psq, true
+}
+
+// KPKProbe returns the exact result of a king and pawn versus king position
+// for the side to move as TBWin, TBDraw or TBLoss, or false if the board
+// holds any other material.
+func KPKProbe(b *Board) (int, bool) {
+	stm, wk, bk, psq, ok := kpkNormalize(b)
+	if !ok {
+		return 0, false
+	}
+
+	if !kpkProbe(stm, wk, bk, psq) {
+		return TBDraw, true
+	}
+	if stm == White {
+		return TBWin, true
+	}
+	return TBLoss, true
+}
+
+// EvaluateKPK is the evaluation hook for king and pawn versus king: 0 for
+// drawn positions and KnownWinScore plus the pawn's advance for won ones,
+// from the side to move's point of view. Returns false for other material.
+func EvaluateKPK(b *Board) (int, bool) {
+	stm, wk, bk, psq, ok := kpkNormalize(b)
+	if !ok {
+		return 0, false
+	}
+
+	if !kpkProbe(stm, wk, bk, psq) {
+		return 0, true
+	}
+
+	score := KnownWinScore + PieceValues[WhitePawn] + psq/8
+	if stm == Black {
+		return -score, true
+	}
+	return score, true
+}
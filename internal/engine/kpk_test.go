@@ -0,0 +1,79 @@
+package engine
+
+import "testing"
+
+func TestKPKProbe(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want int
+	}{
+		// The king on the sixth rank in front of its pawn always wins
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", TBWin},
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", TBLoss},
+		// Further back the opposition decides
+		{"8/4k3/8/4K3/4P3/8/8/8 w - - 0 1", TBDraw},
+		{"8/4k3/8/4K3/4P3/8/8/8 b - - 0 1", TBLoss},
+		// Rook pawns draw when the defending king reaches the corner
+		{"k7/8/1K6/P7/8/8/8/8 w - - 0 1", TBDraw},
+		// The pawn outruns the king
+		{"7k/8/8/8/P7/8/8/K7 w - - 0 1", TBWin},
+		// The same with colors swapped
+		{"k7/8/8/p7/8/8/8/7K b - - 0 1", TBWin},
+		{"8/8/8/4p3/4k3/8/4K3/8 b - - 0 1", TBDraw},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		b := InitBoard(tt.fen)
+		got, ok := KPKProbe(b)
+		if !ok || got != tt.want {
+			t.Errorf("%s: KPKProbe = %d, %v, want %d", tt.fen, got, ok, tt.want)
+		}
+
+		score, ok := EvaluateKPK(b)
+		switch {
+		case !ok:
+			t.Errorf("%s: EvaluateKPK not ok", tt.fen)
+		case tt.want == TBDraw && score != 0,
+			tt.want == TBWin && score < KnownWinScore,
+			tt.want == TBLoss && score > -KnownWinScore:
+			t.Errorf("%s: EvaluateKPK = %d for result %d", tt.fen, score, tt.want)
+		}
+	}
+
+	if _, ok := KPKProbe(InitBoard("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")); ok {
+		t.Error("KPKProbe accepted king and rook versus king")
+	}
+}
+
+// The bitbase must agree with the KPK tablebase in every legal position,
+// with the pawn on either side.
+func TestKPKMatchesTablebase(t *testing.T) {
+	Init("")
+	tables := Tablebases{}
+	table, err := tables.Generate("KPK")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := InitBoard("")
+	squares := make([]int, len(table.pieces))
+	checked := 0
+	for idx, v := range table.values {
+		if v == tbIllegal || !table.setup(b, idx, squares) {
+			continue
+		}
+
+		want := tbResult(v).Outcome
+		for _, pos := range []*Board{b, mirrorBoard(b)} {
+			if got, _ := KPKProbe(pos); got != want {
+				t.Fatalf("%s: KPKProbe = %d, tablebase says %d", pos.ExportFEN(), got, want)
+			}
+			checked++
+		}
+	}
+
+	if checked != 662704 {
+		t.Errorf("checked %d positions, want 662704", checked)
+	}
+}
@@ -0,0 +1,551 @@
+package engine
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+// Labels given to a position by CheckLegality
+const (
+	Legal int = iota
+	Illegal
+	Undetermined
+)
+
+var colorNames = [2]string{"white", "black"}
+
+var pieceNames = [6]string{"pawn", "knight", "bishop", "rook", "queen", "king"}
+
+// LegalityReport is the result of checking whether a position can arise
+// in a game from the starting position.
+type LegalityReport struct {
+	// Legal, Illegal or Undetermined
+	Status int
+	// Reasons the position cannot arise, empty unless it is illegal
+	Issues []string
+	// What the checks could not verify for an undetermined position
+	Notes []string
+}
+
+func (r *LegalityReport) illegal(format string, args ...any) {
+	r.Issues = append(r.Issues, fmt.Sprintf(format, args...))
+}
+
+func (r *LegalityReport) undetermined(format string, args ...any) {
+	r.Notes = append(r.Notes, fmt.Sprintf(format, args...))
+}
+
+// retraction is a possible last move, from is the square the piece came from
+// and cleared is a square the move emptied besides from, the pawn captured
+// en passant or the king of a castling move, -1 if none.
+type retraction struct {
+	from, to, cleared int
+}
+
+// CheckLegality checks whether the position can arise in a game. It looks
+// for impossible kings, checks, pawns, material, castling rights and en
+// passant squares, then retracts the last move to see whether any move
+// could have led to the position. Passing these checks does not prove the
+// position reachable: a position is only labelled legal once a proof game
+// of at most MaxProofPlies plies from the starting position is found, and
+// anything else without an issue is labelled undetermined. Move count
+// parity is therefore only verified for positions close to the start.
+func (b *Board) CheckLegality() *LegalityReport {
+	r := &LegalityReport{}
+
+	if b.checkKings(r) {
+		b.checkEnPassant(r)
+		if len(r.Issues) == 0 {
+			b.checkLastMove(r)
+		}
+	}
+	b.checkPawnRanks(r)
+	b.checkMaterial(White, r)
+	b.checkMaterial(Black, r)
+	b.checkCastling(r)
+
+	if len(r.Issues) == 0 && len(r.Notes) == 0 && !b.hasProofGame() {
+		r.undetermined("no proof game of at most %d plies from the starting position was found", MaxProofPlies)
+	}
+
+	switch {
+	case len(r.Issues) > 0:
+		r.Status = Illegal
+		r.Notes = nil
+	case len(r.Notes) > 0:
+		r.Status = Undetermined
+	default:
+		r.Status = Legal
+	}
+
+	return r
+}
+
+// MaxProofPlies is the longest proof game CheckLegality searches for.
+const MaxProofPlies = 8
+
+// proofSearch looks for a sequence of moves from the starting position that
+// reaches target, remembering positions that cannot reach it in the plies
+// left.
+type proofSearch struct {
+	b, target *Board
+	failed    map[problemKey]bool
+}
+
+// hasProofGame returns true if the position is reached from the starting
+// position in at most MaxProofPlies plies, searched with iterative
+// deepening over the plies with the right parity for the side to move.
+func (b *Board) hasProofGame() bool {
+	s := &proofSearch{
+		b:      InitBoard("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"),
+		target: b,
+		failed: make(map[problemKey]bool),
+	}
+
+	for plies := b.SideToMove; plies <= MaxProofPlies; plies += 2 {
+		if s.search(plies) {
+			return true
+		}
+	}
+	return false
+}
+
+// search returns true if the target is reached in exactly plies plies.
+func (s *proofSearch) search(plies int) bool {
+	if plies == 0 {
+		return s.reached()
+	}
+	if !s.reachable(plies) {
+		return false
+	}
+
+	key := problemKey{s.b.ZobristKey(), plies}
+	if s.failed[key] {
+		return false
+	}
+
+	for _, m := range s.b.GenerateMoves() {
+		undo := s.b.makeMove(m)
+		found := s.search(plies - 1)
+		s.b.unmakeMove(m, undo)
+
+		if found {
+			return true
+		}
+	}
+
+	s.failed[key] = true
+	return false
+}
+
+// reached returns true if the board is the target position. An en passant
+// square is only compared when a pawn can capture en passant, as the
+// position is otherwise the same.
+func (s *proofSearch) reached() bool {
+	b, t := s.b, s.target
+	if b.Pieces != t.Pieces || b.SideToMove != t.SideToMove || b.CastleRights != t.CastleRights {
+		return false
+	}
+	if b.EnPassant == t.EnPassant {
+		return true
+	}
+	return !b.canCaptureEnPassant() && !t.canCaptureEnPassant()
+}
+
+// canCaptureEnPassant returns true if the side to move has a legal en
+// passant capture.
+func (b *Board) canCaptureEnPassant() bool {
+	if b.EnPassant == -1 {
+		return false
+	}
+	return slices.ContainsFunc(b.GenerateMoves(), func(m Move) bool {
+		return m.Flags() == EPCapture
+	})
+}
+
+// reachable returns false if the target certainly cannot be reached in the
+// plies left. Each move puts at most one piece on a square it holds in the
+// target, except castling which places two, and each capture removes one
+// piece of the other side.
+func (s *proofSearch) reachable(plies int) bool {
+	for color := White; color <= Black; color++ {
+		moves := plies / 2
+		if color == s.b.SideToMove {
+			moves = (plies + 1) / 2
+		}
+
+		missing := 0
+		for piece := color * 6; piece <= color*6+WhiteKing; piece++ {
+			missing += (s.target.Pieces[piece] &^ s.b.Pieces[piece]).Count()
+		}
+		castles := [2]int{WhiteKingSide | WhiteQueenSide, BlackKingSide | BlackQueenSide}
+		if missing >= 2 && s.b.CastleRights&castles[color] != 0 {
+			missing--
+		}
+
+		captures := s.b.Occupancy[color^1].Count() - s.target.Occupancy[color^1].Count()
+		if max(missing, captures) > moves {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateKings returns an error if either side does not have exactly one
+// king, the kings are adjacent or the side not to move is in check. Move
+// generation and the solvers assume none of these can happen.
+func (b *Board) ValidateKings() error {
+	r := &LegalityReport{}
+	b.checkKings(r)
+	if len(r.Issues) > 0 {
+		return fmt.Errorf("invalid position: %s", r.Issues[0])
+	}
+	return nil
+}
+
+// checkKings reports missing, extra or adjacent kings and the side not to
+// move being in check. Returns true if both sides have exactly one king, so
+// the remaining king checks can run.
+func (b *Board) checkKings(r *LegalityReport) bool {
+	ok := true
+	for color := White; color <= Black; color++ {
+		switch kings := b.Pieces[color*6+WhiteKing].Count(); kings {
+		case 1:
+		case 0:
+			r.illegal("%s has no king", colorNames[color])
+			ok = false
+		default:
+			r.illegal("%s has %d kings", colorNames[color], kings)
+			ok = false
+		}
+	}
+	if !ok {
+		return false
+	}
+
+	if squareDistance(b.Pieces[WhiteKing].LSB(), b.Pieces[BlackKing].LSB()) <= 1 {
+		r.illegal("kings are on adjacent squares")
+		return false
+	}
+
+	them := b.SideToMove ^ 1
+	if b.checkers(them) != 0 {
+		if b.checkers(b.SideToMove) != 0 {
+			r.illegal("both kings are in check")
+		} else {
+			r.illegal("%s is in check with %s to move", colorNames[them], colorNames[b.SideToMove])
+		}
+	}
+
+	return true
+}
+
+// checkers returns the pieces giving check to the king of color.
+func (b *Board) checkers(color int) Bitboard {
+	king := b.Pieces[color*6+WhiteKing].LSB()
+	return b.AttackersTo(king, b.Occupancy[All]) & b.Occupancy[color^1]
+}
+
+// checkPawnRanks reports pawns on the first or last rank.
+func (b *Board) checkPawnRanks(r *LegalityReport) {
+	const backRanks Bitboard = 0xFF000000000000FF
+	pawns := (b.Pieces[WhitePawn] | b.Pieces[BlackPawn]) & backRanks
+	for pawns != 0 {
+		sq := pawns.PopLSB()
+		r.illegal("%s pawn on %s", colorNames[b.pieceAt(sq)/6], squareName(sq))
+	}
+}
+
+// checkMaterial reports more pawns or pieces than color starts with, more
+// promoted pieces than missing pawns, and pawn structures that need more
+// captures than the other side has lost pieces.
+func (b *Board) checkMaterial(color int, r *LegalityReport) {
+	const lightSquares Bitboard = 0x55AA55AA55AA55AA
+	name := colorNames[color]
+	offset := color * 6
+
+	pawns := b.Pieces[offset+WhitePawn].Count()
+	if pawns > 8 {
+		r.illegal("%s has %d pawns", name, pawns)
+	}
+	if pieces := b.Occupancy[color].Count(); pieces > 16 {
+		r.illegal("%s has %d pieces", name, pieces)
+	}
+
+	// Pieces beyond the starting set must have been promoted, bishops
+	// counted per square color
+	bishops := b.Pieces[offset+WhiteBishop]
+	promoted := max(0, b.Pieces[offset+WhiteKnight].Count()-2) +
+		max(0, (bishops&lightSquares).Count()-1) +
+		max(0, (bishops&^lightSquares).Count()-1) +
+		max(0, b.Pieces[offset+WhiteRook].Count()-2) +
+		max(0, b.Pieces[offset+WhiteQueen].Count()-1)
+	if pawns <= 8 && pawns+promoted > 8 {
+		r.illegal("%s has %d pawns and %d promoted pieces, more than its 8 pawns", name, pawns, promoted)
+	} else if pawns+promoted <= 8 && promoted > 0 {
+		r.undetermined("promotions by %s are not verified (%d pieces beyond the starting set)", name, promoted)
+	}
+
+	captures := pawnCaptures(b.Pieces[offset+WhitePawn])
+	if lost := 16 - b.Occupancy[color^1].Count(); captures > lost {
+		r.illegal("%s pawns need %d or more captures but only %d %s pieces are missing", name, captures, lost, colorNames[color^1])
+	}
+}
+
+// pawnCaptures returns the fewest captures the pawns need to have made to
+// reach their files, assigning each pawn a different starting file.
+func pawnCaptures(pawns Bitboard) int {
+	var files []int
+	for pawns != 0 {
+		files = append(files, pawns.PopLSB()%8)
+	}
+	if len(files) > 8 {
+		return 0
+	}
+
+	// fewest[mask] is the cost of giving the first pawns the starting
+	// files in mask
+	var fewest [1 << 8]int
+	for mask := 1; mask < len(fewest); mask++ {
+		fewest[mask] = -1
+	}
+
+	best := -1
+	for mask := range len(fewest) {
+		pawn := bits.OnesCount(uint(mask))
+		if fewest[mask] < 0 || pawn > len(files) {
+			continue
+		}
+		if pawn == len(files) {
+			if best < 0 || fewest[mask] < best {
+				best = fewest[mask]
+			}
+			continue
+		}
+
+		for file := range 8 {
+			if mask&(1<<file) != 0 {
+				continue
+			}
+			next := mask | 1<<file
+			cost := fewest[mask] + abs(files[pawn]-file)
+			if fewest[next] < 0 || cost < fewest[next] {
+				fewest[next] = cost
+			}
+		}
+	}
+
+	return best
+}
+
+// checkCastling reports castling rights whose king or rook has left its
+// starting square.
+func (b *Board) checkCastling(r *LegalityReport) {
+	rights := []struct {
+		right, piece, sq int
+		symbol           string
+	}{
+		{WhiteKingSide, WhiteKing, 4, "K"}, {WhiteKingSide, WhiteRook, 7, "K"},
+		{WhiteQueenSide, WhiteKing, 4, "Q"}, {WhiteQueenSide, WhiteRook, 0, "Q"},
+		{BlackKingSide, BlackKing, 60, "k"}, {BlackKingSide, BlackRook, 63, "k"},
+		{BlackQueenSide, BlackKing, 60, "q"}, {BlackQueenSide, BlackRook, 56, "q"},
+	}
+
+	for _, c := range rights {
+		if b.CastleRights&c.right != 0 && !b.Pieces[c.piece].Occupied(c.sq) {
+			r.illegal("castling right %s without the %s %s on %s",
+				c.symbol, colorNames[c.piece/6], pieceNames[c.piece%6], squareName(c.sq))
+		}
+	}
+}
+
+// checkEnPassant reports an en passant square that does not match a pawn
+// of the side not to move that just moved two squares.
+func (b *Board) checkEnPassant(r *LegalityReport) {
+	ep := b.EnPassant
+	if ep == -1 {
+		return
+	}
+
+	them := b.SideToMove ^ 1
+	rank, pawn, origin := 5, ep-8, ep+8
+	if b.SideToMove == Black {
+		rank, pawn, origin = 2, ep+8, ep-8
+	}
+
+	if ep < 0 || ep > 63 || ep/8 != rank {
+		r.illegal("en passant square is not on the %s rank", [2]string{"sixth", "third"}[b.SideToMove])
+		return
+	}
+	if !b.Pieces[them*6+WhitePawn].Occupied(pawn) {
+		r.illegal("en passant square %s without a %s pawn on %s", squareName(ep), colorNames[them], squareName(pawn))
+	}
+	if b.Occupancy[All].Occupied(ep) || b.Occupancy[All].Occupied(origin) {
+		r.illegal("en passant square %s with a piece on the double push path", squareName(ep))
+	}
+}
+
+// checkLastMove retracts the last move of the side not to move and reports
+// the position if no move could have led to it, including checks that no
+// single move can give. With an en passant square the last move must have
+// been the double pawn push.
+func (b *Board) checkLastMove(r *LegalityReport) {
+	us := b.SideToMove
+	them := us ^ 1
+	king := b.Pieces[us*6+WhiteKing].LSB()
+	checkers := b.checkers(us)
+
+	var candidates []retraction
+	if b.EnPassant != -1 {
+		push := 8
+		if them == Black {
+			push = -8
+		}
+		candidates = []retraction{{b.EnPassant - push, b.EnPassant + push, -1}}
+	} else {
+		candidates = b.retractions(them)
+	}
+
+	for _, c := range candidates {
+		if b.explainsCheck(c, king, checkers) {
+			return
+		}
+	}
+
+	var squares []int
+	for bb := checkers; bb != 0; {
+		squares = append(squares, bb.PopLSB())
+	}
+
+	switch len(squares) {
+	case 0:
+		r.illegal("%s has no possible last move", colorNames[them])
+	case 1:
+		r.illegal("check by the %s on %s cannot have been given by %s's last move",
+			pieceNames[b.pieceAt(squares[0])%6], squareName(squares[0]), colorNames[them])
+	case 2:
+		r.illegal("double check by the %s on %s and the %s on %s cannot be given by one move",
+			pieceNames[b.pieceAt(squares[0])%6], squareName(squares[0]),
+			pieceNames[b.pieceAt(squares[1])%6], squareName(squares[1]))
+	default:
+		r.illegal("%s king is attacked by %d pieces", colorNames[us], len(squares))
+	}
+}
+
+// explainsCheck returns true if the last move could have given every check:
+// each checker is either the piece that moved or a slider whose line to the
+// king the move opened.
+func (b *Board) explainsCheck(c retraction, king int, checkers Bitboard) bool {
+	for checkers != 0 {
+		sq := checkers.PopLSB()
+		if sq == c.to {
+			continue
+		}
+
+		line := between(sq, king)
+		if !line.Occupied(c.from) && (c.cleared == -1 || !line.Occupied(c.cleared)) {
+			return false
+		}
+	}
+	return true
+}
+
+// between returns the squares strictly between two squares on a shared
+// rank, file or diagonal, or an empty bitboard if they share none.
+func between(a, b int) Bitboard {
+	from, to := Bitboard(1)<<a, Bitboard(1)<<b
+	if RookAttacks(a, to)&to != 0 {
+		return RookAttacks(a, to) & RookAttacks(b, from)
+	}
+	if BishopAttacks(a, to)&to != 0 {
+		return BishopAttacks(a, to) & BishopAttacks(b, from)
+	}
+	return 0
+}
+
+// retractions returns the moves color could have made last. Captures are
+// only retracted if the other side has a piece missing to put back, and
+// whether the retracted position leaves the other side in check is not
+// verified.
+func (b *Board) retractions(color int) []retraction {
+	var moves []retraction
+	offset := color * 6
+	empty := ^b.Occupancy[All]
+	uncapture := b.Occupancy[color^1].Count() < 16
+
+	push, second, last := 8, 1, 7
+	if color == Black {
+		push, second, last = -8, 6, 0
+	}
+
+	for piece := offset; piece <= offset+WhiteKing; piece++ {
+		bb := b.Pieces[piece]
+		for bb != 0 {
+			to := bb.PopLSB()
+
+			var from Bitboard
+			switch piece % 6 {
+			case WhitePawn:
+				if to/8 == second || to/8 == 0 || to/8 == 7 {
+					continue
+				}
+				if empty.Occupied(to - push) {
+					from.Set(to - push)
+					if to/8 == second+2*(push/8) && empty.Occupied(to-2*push) {
+						from.Set(to - 2*push)
+					}
+				}
+				if uncapture {
+					from |= PawnAttacks(to, color^1) & empty
+				}
+			case WhiteKnight:
+				from = KnightMoves[to] & empty
+			case WhiteBishop:
+				from = BishopAttacks(to, b.Occupancy[All]) & empty
+			case WhiteRook:
+				from = RookAttacks(to, b.Occupancy[All]) & empty
+			case WhiteQueen:
+				from = QueenAttacks(to, b.Occupancy[All]) & empty
+			case WhiteKing:
+				from = KingMoves[to] & empty &^ KingMoves[b.Pieces[(color^1)*6+WhiteKing].LSB()]
+			}
+
+			// Pieces on the last rank may have been promoted from the
+			// seventh rank
+			if piece%6 != WhitePawn && piece%6 != WhiteKing && to/8 == last {
+				if empty.Occupied(to - push) {
+					from.Set(to - push)
+				}
+				if uncapture {
+					from |= PawnAttacks(to, color^1) & empty
+				}
+			}
+
+			for from != 0 {
+				moves = append(moves, retraction{from.PopLSB(), to, -1})
+			}
+
+			// En passant captures also emptied the captured pawn's square
+			if piece%6 == WhitePawn && to/8 == second+4*(push/8) && empty.Occupied(to-push) && uncapture {
+				ep := PawnAttacks(to, color^1) & empty
+				for ep != 0 {
+					moves = append(moves, retraction{ep.PopLSB(), to, to - push})
+				}
+			}
+		}
+	}
+
+	// Castling moved the rook next to the king's starting square
+	castles := []struct{ king, rook, kingFrom, rookFrom int }{
+		{6, 5, 4, 7}, {2, 3, 4, 0}, {62, 61, 60, 63}, {58, 59, 60, 56},
+	}
+	for _, c := range castles[color*2 : color*2+2] {
+		if b.Pieces[offset+WhiteKing].Occupied(c.king) && b.Pieces[offset+WhiteRook].Occupied(c.rook) &&
+			empty.Occupied(c.kingFrom) && empty.Occupied(c.rookFrom) {
+			moves = append(moves, retraction{c.rookFrom, c.rook, c.kingFrom})
+		}
+	}
+
+	return moves
+}
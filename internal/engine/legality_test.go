@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckLegality(t *testing.T) {
+	tests := []struct {
+		name   string
+		fen    string
+		status int
+		// Substring of the first issue or note, empty for legal positions
+		reason string
+	}{
+		{"start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", Legal, ""},
+		{"after 1.e4 e5", "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", Legal, ""},
+		{"en passant square omitted", "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", Legal, ""},
+		{"after 1.Nf3 Nf6 2.Ng1 Ng8", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 4 3", Legal, ""},
+		{"start with black to move", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1", Undetermined, "no proof game"},
+		{"two white pawn moves with black to move", "rnbqkbnr/pppppppp/8/8/8/PP6/2PPPPPP/RNBQKBNR b KQkq - 0 1", Undetermined, "no proof game"},
+		{"far from the start", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", Undetermined, "no proof game"},
+		{"promoted piece", "4k3/8/8/8/8/8/8/QQ2K3 w - - 0 1", Undetermined, "promotions by white"},
+
+		{"missing king", "8/8/8/8/8/8/8/K7 w - - 0 1", Illegal, "black has no king"},
+		{"two kings", "k6k/8/8/8/8/8/8/K7 w - - 0 1", Illegal, "black has 2 kings"},
+		{"adjacent kings", "8/8/8/8/8/8/8/Kk6 w - - 0 1", Illegal, "adjacent"},
+		{"both kings in check", "4k2R/8/8/8/8/8/8/4K2r w - - 0 1", Illegal, "both kings are in check"},
+		{"side not to move in check", "4k3/8/8/8/8/8/8/4R1K1 w - - 0 1", Illegal, "black is in check with white to move"},
+		{"double check by two knights", "4k3/8/3N1N2/8/8/8/8/6K1 b - - 0 1", Illegal, "cannot be given by one move"},
+		{"impossible double check", "4k3/8/8/8/B7/8/8/4R1K1 b - - 0 1", Illegal, "cannot be given by one move"},
+		{"triple check", "4k3/8/3N4/8/B7/8/8/4R1K1 b - - 0 1", Illegal, "attacked by 3 pieces"},
+		{"pawn on the back rank", "4k3/8/8/8/8/8/8/P3K3 w - - 0 1", Illegal, "white pawn on a1"},
+		{"nine pawns", "4k3/8/8/8/P7/8/PPPPPPPP/4K3 w - - 0 1", Illegal, "white has 9 pawns"},
+		{"too many promoted pieces", "4k3/8/8/8/8/8/PPPPPPPP/QQ2K3 w - - 0 1", Illegal, "promoted pieces"},
+		{"en passant on the wrong rank", "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e5 0 2", Illegal, "not on the sixth rank"},
+		{"en passant without a pawn", "rnbqkbnr/pppp1ppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", Illegal, "without a black pawn on e5"},
+		{"pawn captures exceed missing pieces", "rnbqkbnr/pppppppp/8/8/8/1P6/1PPPPPPP/RNBQKBNR w KQkq - 0 1", Illegal, "need 1 or more captures but only 0 black pieces are missing"},
+		{"castling after the rook moved", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1", Illegal, "castling right K"},
+		{"no possible last move", "6bk/5ppp/8/8/8/8/8/K7 w - - 0 1", Illegal, "black has no possible last move"},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := InitBoard(tt.fen)
+			r := b.CheckLegality()
+			if r.Status != tt.status {
+				t.Fatalf("Status = %d, want %d (issues %q, notes %q)", r.Status, tt.status, r.Issues, r.Notes)
+			}
+
+			reasons := append(r.Issues, r.Notes...)
+			if tt.reason == "" {
+				if len(reasons) > 0 {
+					t.Errorf("reasons = %q, want none", reasons)
+				}
+				return
+			}
+			if !strings.Contains(strings.Join(reasons, "\n"), tt.reason) {
+				t.Errorf("reasons = %q, want one containing %q", reasons, tt.reason)
+			}
+		})
+	}
+}
@@ -10,6 +10,14 @@ type Undo struct {
 // MakeMove makes a move on the board and returns an undo struct
 // for UnmakeMove
 func (b *Board) MakeMove(m Move) Undo {
+	undo := b.makeMove(m)
+	b.PositionCount[b.GetPositionKey()]++
+	return undo
+}
+
+// makeMove makes a move without recording the resulting position
+// for threefold repetition, for moves that are unmade right away.
+func (b *Board) makeMove(m Move) Undo {
 	from, to, flags := m.From(), m.To(), m.Flags()
 
 	undo := Undo{
@@ -168,14 +176,23 @@ func (b *Board) MakeMove(m Move) Undo {
 
 	b.Occupancy[All] = b.Occupancy[White] | b.Occupancy[Black]
 
-	b.PositionCount[b.GetPositionKey()]++
-
 	return undo
 }
 
 // UnmakeMove undoes a move on the board, using the undo struct
 // returned by MakeMove and the move to be unmade.
 func (b *Board) UnmakeMove(m Move, undo Undo) {
+	key := b.GetPositionKey()
+	b.PositionCount[key]--
+	if b.PositionCount[key] == 0 {
+		delete(b.PositionCount, key)
+	}
+
+	b.unmakeMove(m, undo)
+}
+
+// unmakeMove undoes a move made with makeMove.
+func (b *Board) unmakeMove(m Move, undo Undo) {
 	if b.SideToMove == White {
 		b.FullMove--
 		b.SideToMove = Black
@@ -259,10 +276,4 @@ func (b *Board) UnmakeMove(m Move, undo Undo) {
 	b.HalfMove = undo.HalfMove
 
 	b.Occupancy[All] = b.Occupancy[White] | b.Occupancy[Black]
-
-	key := b.GetPositionKey()
-	b.PositionCount[key]--
-	if b.PositionCount[key] == 0 {
-		delete(b.PositionCount, key)
-	}
 }
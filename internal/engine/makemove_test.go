@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"maps"
+	"testing"
+)
+
+var makeMoveFENs = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+}
+
+func TestMakeUnmakeRoundTrip(t *testing.T) {
+	Init("")
+	for _, fen := range makeMoveFENs {
+		b := InitBoard(fen)
+		b.MakeMove(b.GenerateMoves()[0])
+		before := b.ExportFEN()
+		counts := maps.Clone(b.PositionCount)
+
+		for _, m := range b.GenerateMoves() {
+			undo := b.MakeMove(m)
+			b.UnmakeMove(m, undo)
+
+			if got := b.ExportFEN(); got != before {
+				t.Errorf("%s %v: FEN after unmake = %q, want %q", fen, m, got, before)
+			}
+			if !maps.Equal(b.PositionCount, counts) {
+				t.Errorf("%s %v: PositionCount after unmake = %v, want %v", fen, m, b.PositionCount, counts)
+			}
+		}
+	}
+}
+
+func TestGenerateMovesKeepsPositionCount(t *testing.T) {
+	Init("")
+	for _, fen := range makeMoveFENs {
+		b := InitBoard(fen)
+		b.MakeMove(b.GenerateMoves()[0])
+		counts := maps.Clone(b.PositionCount)
+
+		b.GenerateMoves()
+		if !maps.Equal(b.PositionCount, counts) {
+			t.Errorf("%s: PositionCount after GenerateMoves = %v, want %v", fen, b.PositionCount, counts)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package engine
+
+// MateNode is a move in a forced mate solution. Moves by the attacking side
+// list every legal defence as replies, and each defence has the attacking
+// move that keeps the mate on track as its only reply. A move without
+// replies delivers mate.
+type MateNode struct {
+	Move    Move
+	SAN     string
+	Replies []*MateNode
+}
+
+// MateSolution is a proven forced mate for the side to move.
+type MateSolution struct {
+	// Number of moves by the attacking side, including the mating move
+	Moves int
+	// The first move of the solution with the full tree below it
+	Key *MateNode
+}
+
+// mateEntry records what is known about a position with the attacking side
+// to move.
+type mateEntry struct {
+	// Smallest number of moves a mate was found in, 0 if none yet
+	proven int
+	// Largest number of moves a mate was shown not to exist in
+	disproven int
+}
+
+// mateSolver is a depth-first search for forced mates that only looks at
+// whether positions are mate, so it needs no evaluation. Results are
+// cached in its own hash table keyed by the Zobrist key.
+type mateSolver struct {
+	b          *Board
+	checksOnly bool
+	table      map[uint64]mateEntry
+}
+
+// SolveMate searches for the shortest forced mate in at most n moves for the
+// side to move, considering every defence. With checksOnly set the
+// attacking side is limited to checking moves. Returns nil if there is no
+// mate within n moves.
+func (b *Board) SolveMate(n int, checksOnly bool) *MateSolution {
+	s := &mateSolver{
+		b:          b,
+		checksOnly: checksOnly,
+		table:      make(map[uint64]mateEntry),
+	}
+
+	for moves := 1; moves <= n; moves++ {
+		if s.attack(moves) {
+			return &MateSolution{Moves: moves, Key: s.attackTree(moves)}
+		}
+	}
+
+	return nil
+}
+
+// attackMoves returns the attacking side's candidate moves, checks first.
+// Quiet moves are dropped when only checks are allowed or when the next
+// move has to be mate.
+func (s *mateSolver) attackMoves(n int) []Move {
+	moves := s.b.GenerateMoves()
+	checks := make([]Move, 0, len(moves))
+	others := make([]Move, 0, len(moves))
+
+	for _, m := range moves {
+		undo := s.b.makeMove(m)
+		check := s.b.IsInCheck()
+		s.b.unmakeMove(m, undo)
+
+		if check {
+			checks = append(checks, m)
+		} else if !s.checksOnly && n > 1 {
+			others = append(others, m)
+		}
+	}
+
+	return append(checks, others...)
+}
+
+// attack returns true if the side to move can force mate in n moves.
+func (s *mateSolver) attack(n int) bool {
+	if n <= 0 {
+		return false
+	}
+
+	key := s.b.ZobristKey()
+	entry := s.table[key]
+	if entry.proven != 0 && entry.proven <= n {
+		return true
+	}
+	if entry.disproven >= n {
+		return false
+	}
+
+	found := false
+	for _, m := range s.attackMoves(n) {
+		undo := s.b.makeMove(m)
+		found = s.defend(n)
+		s.b.unmakeMove(m, undo)
+
+		if found {
+			break
+		}
+	}
+
+	entry = s.table[key]
+	if found {
+		if entry.proven == 0 || n < entry.proven {
+			entry.proven = n
+		}
+	} else {
+		entry.disproven = max(entry.disproven, n)
+	}
+	s.table[key] = entry
+
+	return found
+}
+
+// defend returns true if every move of the side to move runs into mate, with
+// the attacking side having n-1 moves left. Checkmate counts as lost and
+// stalemate as saved.
+func (s *mateSolver) defend(n int) bool {
+	moves := s.b.GenerateMoves()
+	if len(moves) == 0 {
+		return s.b.IsInCheck()
+	}
+
+	for _, m := range moves {
+		undo := s.b.makeMove(m)
+		mated := s.attack(n - 1)
+		s.b.unmakeMove(m, undo)
+
+		if !mated {
+			return false
+		}
+	}
+
+	return true
+}
+
+// attackTree returns the first attacking move that forces mate in n moves
+// with every defence and its fastest continuation below it.
+func (s *mateSolver) attackTree(n int) *MateNode {
+	for _, m := range s.attackMoves(n) {
+		undo := s.b.makeMove(m)
+		mates := s.defend(n)
+		s.b.unmakeMove(m, undo)
+
+		if !mates {
+			continue
+		}
+
+		node := &MateNode{Move: m, SAN: s.b.SAN(m)}
+		undo = s.b.makeMove(m)
+		for _, reply := range s.b.GenerateMoves() {
+			defence := &MateNode{Move: reply, SAN: s.b.SAN(reply)}
+
+			undo := s.b.makeMove(reply)
+			for moves := 1; moves < n; moves++ {
+				if s.attack(moves) {
+					defence.Replies = []*MateNode{s.attackTree(moves)}
+					break
+				}
+			}
+			s.b.unmakeMove(reply, undo)
+
+			node.Replies = append(node.Replies, defence)
+		}
+		s.b.unmakeMove(m, undo)
+
+		return node
+	}
+
+	return nil
+}
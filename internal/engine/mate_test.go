@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+// mateTree writes the solution tree on one line, each defence in brackets
+// followed by the attacking side's answer.
+func mateTree(node *MateNode) string {
+	var s strings.Builder
+	s.WriteString(node.SAN)
+	for _, defence := range node.Replies {
+		s.WriteString(" (" + defence.SAN)
+		if len(defence.Replies) > 0 {
+			s.WriteString(" " + mateTree(defence.Replies[0]))
+		}
+		s.WriteString(")")
+	}
+	return s.String()
+}
+
+func TestSolveMate(t *testing.T) {
+	tests := []struct {
+		name       string
+		fen        string
+		n          int
+		checksOnly bool
+		// Solution tree, empty if there is no mate
+		want  string
+		moves int
+	}{
+		{
+			"mate in one", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 2, false,
+			"Ra8#", 1,
+		},
+		{
+			"mate in three", "r5rk/5p1p/5R2/4B3/8/8/7P/7K w - - 0 1", 3, false,
+			"Ra6+ (f6 Bxf6+ (Rg7 Rxa8#)) (Rg7 Rxa8#)", 3,
+		},
+		{
+			"shortest mate found first", "r5rk/5p1p/5R2/4B3/8/8/7P/7K w - - 0 1", 5, false,
+			"Ra6+ (f6 Bxf6+ (Rg7 Rxa8#)) (Rg7 Rxa8#)", 3,
+		},
+		{
+			"quiet key", "kbK5/pp6/1P6/8/8/8/8/R7 w - - 0 1", 2, false,
+			"Ra6 (bxa6 b7#) (Bh2 Rxa7#) (Bg3 Rxa7#) (Bf4 Rxa7#) (Be5 Rxa7#) (Bd6 Rxa7#) (Bc7 Rxa7#)", 2,
+		},
+		{"quiet key with checks only", "kbK5/pp6/1P6/8/8/8/8/R7 w - - 0 1", 3, true, "", 0},
+		{"too few moves", "r5rk/5p1p/5R2/4B3/8/8/7P/7K w - - 0 1", 2, false, "", 0},
+		{"no mate", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 2, false, "", 0},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := InitBoard(tt.fen)
+			solution := b.SolveMate(tt.n, tt.checksOnly)
+			if tt.want == "" {
+				if solution != nil {
+					t.Errorf("SolveMate = mate in %d %s, want nil", solution.Moves, mateTree(solution.Key))
+				}
+				return
+			}
+
+			if solution == nil {
+				t.Fatal("SolveMate = nil")
+			}
+			if solution.Moves != tt.moves {
+				t.Errorf("Moves = %d, want %d", solution.Moves, tt.moves)
+			}
+			if got := mateTree(solution.Key); got != tt.want {
+				t.Errorf("tree =\n%s\nwant\n%s", got, tt.want)
+			}
+			if got := b.ExportFEN(); got != tt.fen {
+				t.Errorf("board after solving = %s, want %s", got, tt.fen)
+			}
+		})
+	}
+}
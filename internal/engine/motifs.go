@@ -0,0 +1,327 @@
+package engine
+
+import "slices"
+
+// Tactical motifs found by Threats and Motifs
+const (
+	Hanging int = iota
+	AbsolutePin
+	RelativePin
+	Skewer
+	Fork
+	DiscoveredAttack
+	DiscoveredCheck
+	OverloadedDefender
+	BackRankWeakness
+)
+
+// Motif is a tactical pattern on the board that Color can exploit. Squares
+// holds the pieces involved, starting with the key piece:
+//   - Hanging: the attacked and undefended piece, then its attackers
+//   - AbsolutePin, RelativePin, Skewer: the slider, the piece in front and
+//     the piece behind it
+//   - Fork: the forking piece, then the pieces it attacks
+//   - DiscoveredAttack, DiscoveredCheck: the piece that moved, the slider
+//     it uncovered and the piece the slider now attacks
+//   - OverloadedDefender: the defender, then the attacked pieces it alone
+//     defends
+//   - BackRankWeakness: the king
+type Motif struct {
+	Kind    int
+	Color   int
+	Squares []int
+}
+
+// Threats returns the motifs in the position for both sides: hanging
+// pieces, pins, skewers, forks, overloaded defenders and back-rank
+// weaknesses, white's first.
+func (b *Board) Threats() []Motif {
+	var motifs []Motif
+	for color := White; color <= Black; color++ {
+		motifs = append(motifs, b.hangingPieces(color)...)
+		motifs = append(motifs, b.lineMotifs(color)...)
+		motifs = append(motifs, b.forks(color)...)
+		motifs = append(motifs, b.overloadedDefenders(color)...)
+		if b.backRankWeak(color ^ 1) {
+			king := b.Pieces[(color^1)*6+WhiteKing].LSB()
+			motifs = append(motifs, Motif{BackRankWeakness, color, []int{king}})
+		}
+	}
+	return motifs
+}
+
+// Motifs returns the motifs created by the legal move: those in the
+// position after the move that were not there before, along with the
+// discovered attacks and checks it gives. Motifs against the moving side,
+// such as the moved piece left hanging, are included.
+func (b *Board) Motifs(m Move) []Motif {
+	before := b.Threats()
+	occupancy := b.Occupancy[All]
+
+	undo := b.makeMove(m)
+	defer b.unmakeMove(m, undo)
+
+	motifs := motifDiff(b.Threats(), before)
+	return append(motifs, b.discoveries(m.To(), occupancy)...)
+}
+
+func (m Motif) equal(other Motif) bool {
+	return m.Kind == other.Kind && m.Color == other.Color && slices.Equal(m.Squares, other.Squares)
+}
+
+// motifDiff returns the motifs in a that are not in b.
+func motifDiff(a, b []Motif) []Motif {
+	var diff []Motif
+	for _, motif := range a {
+		if !slices.ContainsFunc(b, motif.equal) {
+			diff = append(diff, motif)
+		}
+	}
+	return diff
+}
+
+// pieceAttacks returns the squares attacked by the piece, an index into
+// Board.Pieces, standing on sq given the occupancy.
+func pieceAttacks(piece, sq int, occupancy Bitboard) Bitboard {
+	switch piece % 6 {
+	case WhitePawn:
+		return PawnAttacks(sq, piece/6)
+	case WhiteKnight:
+		return KnightMoves[sq]
+	case WhiteBishop:
+		return BishopAttacks(sq, occupancy)
+	case WhiteRook:
+		return RookAttacks(sq, occupancy)
+	case WhiteQueen:
+		return QueenAttacks(sq, occupancy)
+	default:
+		return KingMoves[sq]
+	}
+}
+
+// defenders returns the pieces of color protecting sq.
+func (b *Board) defenders(sq, color int) Bitboard {
+	return b.AttackersTo(sq, b.Occupancy[All]) & b.Occupancy[color]
+}
+
+// exposed returns true if the piece on sq can be won: it is attacked and
+// either undefended or attacked by a cheaper piece.
+func (b *Board) exposed(sq int) bool {
+	piece := b.pieceAt(sq)
+	color := piece / 6
+	attackers := b.defenders(sq, color^1)
+	if attackers == 0 || piece%6 == WhiteKing {
+		return false
+	}
+	if b.defenders(sq, color) == 0 {
+		return true
+	}
+
+	_, cheapest := b.leastValuableAttacker(attackers, color^1)
+	return PieceValues[cheapest%6] < PieceValues[piece%6]
+}
+
+// worthAttacking returns true if attacking the piece on target with the
+// given piece threatens to win something: the target is the king, is worth
+// more than the attacker or is undefended.
+func (b *Board) worthAttacking(piece, target int) bool {
+	victim := b.pieceAt(target)
+	return victim%6 == WhiteKing ||
+		PieceValues[victim%6] > PieceValues[piece%6] ||
+		b.defenders(target, victim/6) == 0
+}
+
+// bitboardSquares returns the squares set in the bitboard, lowest first.
+func bitboardSquares(bb Bitboard) []int {
+	var sqs []int
+	for bb != 0 {
+		sqs = append(sqs, bb.PopLSB())
+	}
+	return sqs
+}
+
+// hangingPieces returns the pieces of the other side that color attacks
+// and nothing defends.
+func (b *Board) hangingPieces(color int) []Motif {
+	var motifs []Motif
+	targets := b.Occupancy[color^1] &^ b.Pieces[(color^1)*6+WhiteKing]
+	for targets != 0 {
+		sq := targets.PopLSB()
+		attackers := b.defenders(sq, color)
+		if attackers != 0 && b.defenders(sq, color^1) == 0 {
+			motifs = append(motifs, Motif{Hanging, color, append([]int{sq}, bitboardSquares(attackers)...)})
+		}
+	}
+	return motifs
+}
+
+// minValueGap is how much more a piece has to be worth than another for a
+// pin or skewer between them to matter, so a knight is not pinned to a
+// bishop.
+const minValueGap = 50
+
+// lineMotifs returns the pins and skewers by color's sliders: a slider
+// attacking a piece with another piece of the same color behind it on the
+// line. A pin has the more valuable piece behind, a skewer in front. Only
+// pins against the king hold when the slider can simply be won.
+func (b *Board) lineMotifs(color int) []Motif {
+	var motifs []Motif
+	for piece := color*6 + WhiteBishop; piece <= color*6+WhiteQueen; piece++ {
+		sliders := b.Pieces[piece]
+		for sliders != 0 {
+			sq := sliders.PopLSB()
+			exposed := b.exposed(sq)
+			occupancy := b.Occupancy[All]
+			attacks := pieceAttacks(piece, sq, occupancy)
+
+			fronts := attacks & b.Occupancy[color^1]
+			for fronts != 0 {
+				front := fronts.PopLSB()
+				occupancy.Clear(front)
+				behind := pieceAttacks(piece, sq, occupancy) &^ attacks & b.Occupancy[color^1]
+				occupancy.Set(front)
+				if behind == 0 {
+					continue
+				}
+
+				back := behind.LSB()
+				frontValue := PieceValues[b.pieceAt(front)%6]
+				backValue := PieceValues[b.pieceAt(back)%6]
+				line := []int{sq, front, back}
+
+				switch {
+				case b.pieceAt(back)%6 == WhiteKing:
+					motifs = append(motifs, Motif{AbsolutePin, color, line})
+				case exposed:
+				case backValue > frontValue+minValueGap:
+					motifs = append(motifs, Motif{RelativePin, color, line})
+				case frontValue > backValue+minValueGap && b.worthAttacking(piece, back):
+					motifs = append(motifs, Motif{Skewer, color, line})
+				}
+			}
+		}
+	}
+	return motifs
+}
+
+// forks returns color's pieces that cannot simply be won and attack two or
+// more pieces worth attacking.
+func (b *Board) forks(color int) []Motif {
+	var motifs []Motif
+	for piece := color * 6; piece <= color*6+WhiteKing; piece++ {
+		forkers := b.Pieces[piece]
+		for forkers != 0 {
+			sq := forkers.PopLSB()
+			if b.exposed(sq) {
+				continue
+			}
+
+			targets := pieceAttacks(piece, sq, b.Occupancy[All]) & b.Occupancy[color^1]
+			forked := []int{sq}
+			for targets != 0 {
+				if target := targets.PopLSB(); b.worthAttacking(piece, target) {
+					forked = append(forked, target)
+				}
+			}
+			if len(forked) >= 3 {
+				motifs = append(motifs, Motif{Fork, color, forked})
+			}
+		}
+	}
+	return motifs
+}
+
+// overloadedDefenders returns the other side's pieces that are the only
+// defender of two or more pieces color attacks.
+func (b *Board) overloadedDefenders(color int) []Motif {
+	defended := make(map[int][]int)
+	targets := b.Occupancy[color^1] &^ b.Pieces[(color^1)*6+WhiteKing]
+	for targets != 0 {
+		sq := targets.PopLSB()
+		if b.defenders(sq, color) == 0 {
+			continue
+		}
+		if defenders := b.defenders(sq, color^1); defenders.Count() == 1 {
+			defended[defenders.LSB()] = append(defended[defenders.LSB()], sq)
+		}
+	}
+
+	var motifs []Motif
+	for sq := range 64 {
+		if len(defended[sq]) >= 2 {
+			motifs = append(motifs, Motif{OverloadedDefender, color, append([]int{sq}, defended[sq]...)})
+		}
+	}
+	return motifs
+}
+
+// backRankWeak returns true if the king of color is boxed in on its back
+// rank while the other side has a rook or queen to mate it with and no rook
+// or queen of its own guards the back rank.
+func (b *Board) backRankWeak(color int) bool {
+	them := color ^ 1
+	if b.Pieces[them*6+WhiteRook]|b.Pieces[them*6+WhiteQueen] == 0 || !b.backRankBoxed(color) {
+		return false
+	}
+
+	rank := Bitboard(0xFF) << (8 * (b.Pieces[color*6+WhiteKing].LSB() / 8))
+	return (b.Pieces[color*6+WhiteRook]|b.Pieces[color*6+WhiteQueen])&rank == 0
+}
+
+// backRankBoxed returns true if the king of color is on its back rank with
+// its squares on the next rank blocked by its own pieces or attacked.
+func (b *Board) backRankBoxed(color int) bool {
+	them := color ^ 1
+	back, forward := 0, 1
+	if color == Black {
+		back, forward = 7, 6
+	}
+
+	king := b.Pieces[color*6+WhiteKing].LSB()
+	if king/8 != back {
+		return false
+	}
+
+	escapes := KingMoves[king] & (Bitboard(0xFF) << (8 * forward))
+	if escapes&b.Occupancy[color] == 0 {
+		return false
+	}
+
+	open := escapes &^ b.Occupancy[color]
+	for open != 0 {
+		if !b.IsSqAttacked(open.PopLSB(), them) {
+			return false
+		}
+	}
+	return true
+}
+
+// discoveries returns the discovered attacks and checks given by a move to
+// sq, made from a position with the given occupancy: lines opened for the
+// moving side's other sliders to a piece worth attacking.
+func (b *Board) discoveries(sq int, before Bitboard) []Motif {
+	var motifs []Motif
+	color := b.SideToMove ^ 1
+	after := b.Occupancy[All]
+
+	for piece := color*6 + WhiteBishop; piece <= color*6+WhiteQueen; piece++ {
+		sliders := b.Pieces[piece]
+		sliders.Clear(sq)
+		for sliders != 0 {
+			slider := sliders.PopLSB()
+			opened := pieceAttacks(piece, slider, after) &^ pieceAttacks(piece, slider, before)
+
+			targets := opened & b.Occupancy[color^1]
+			for targets != 0 {
+				target := targets.PopLSB()
+				switch {
+				case b.pieceAt(target)%6 == WhiteKing:
+					motifs = append(motifs, Motif{DiscoveredCheck, color, []int{sq, slider, target}})
+				case b.worthAttacking(piece, target):
+					motifs = append(motifs, Motif{DiscoveredAttack, color, []int{sq, slider, target}})
+				}
+			}
+		}
+	}
+	return motifs
+}
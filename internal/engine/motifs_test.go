@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestThreats(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want []Motif
+	}{
+		{
+			"hanging piece", "4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1",
+			[]Motif{{Hanging, White, []int{35, 3}}},
+		},
+		{
+			"absolute pin", "4k3/4n3/8/8/8/8/8/4R1K1 w - - 0 1",
+			[]Motif{{AbsolutePin, White, []int{4, 52, 60}}},
+		},
+		{
+			"relative pin", "4k3/8/8/8/R1n2q2/8/8/4K3 w - - 0 1",
+			[]Motif{{RelativePin, White, []int{24, 26, 29}}},
+		},
+		{
+			"skewer", "4q3/8/8/4k3/8/8/8/K3R3 b - - 0 1",
+			[]Motif{{Skewer, White, []int{4, 36, 60}}},
+		},
+		{
+			"fork", "r3k3/2N5/8/8/8/8/8/4K3 b - - 0 1",
+			[]Motif{{Hanging, White, []int{56, 50}}, {Fork, White, []int{50, 56, 60}}},
+		},
+		{
+			"overloaded defender", "3qk3/8/8/n7/7b/8/8/R5KR w - - 0 1",
+			[]Motif{{OverloadedDefender, White, []int{59, 31, 32}}},
+		},
+		{
+			"back-rank weakness", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+			[]Motif{{BackRankWeakness, White, []int{62}}},
+		},
+		// A knight is not pinned to a bishop of about the same value
+		{"no pin below minValueGap", "4k3/8/8/4b3/8/2n5/8/B3K3 w - - 0 1", nil},
+		// A rook that the pawn can take pins nothing but the king
+		{
+			"no pin by an exposed slider", "4k3/8/8/1p6/R1n2q2/8/8/4K3 w - - 0 1",
+			[]Motif{{Hanging, Black, []int{24, 33}}},
+		},
+		// A forking knight that the bishop can take forks nothing
+		{
+			"no fork by an exposed piece", "rb2k3/2N5/8/8/8/8/8/4K3 b - - 0 1",
+			[]Motif{{Hanging, White, []int{56, 50}}, {Hanging, Black, []int{50, 57}}},
+		},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := InitBoard(tt.fen)
+			if got := b.Threats(); !slices.EqualFunc(got, tt.want, Motif.equal) {
+				t.Errorf("Threats = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMotifs(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string
+		want Motif
+	}{
+		{
+			"discovered check", "4k3/8/8/8/4N3/8/8/K3R3 w - - 0 1", "e4c3",
+			Motif{DiscoveredCheck, White, []int{18, 4, 60}},
+		},
+		{
+			"discovered attack", "4k3/8/8/5r2/8/8/2N5/1B2K3 w - - 0 1", "c2a3",
+			Motif{DiscoveredAttack, White, []int{16, 1, 37}},
+		},
+		{
+			"fork", "r3k3/8/8/3N4/8/8/8/4K3 w - - 0 1", "d5c7",
+			Motif{Fork, White, []int{50, 56, 60}},
+		},
+		{
+			"moved piece left hanging", "4k3/8/1n6/8/8/8/8/3RK3 w - - 0 1", "d1d5",
+			Motif{Hanging, Black, []int{35, 41}},
+		},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := InitBoard(tt.fen)
+			m, err := b.ParseUCI(tt.move)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := b.Motifs(m)
+			if !slices.ContainsFunc(got, tt.want.equal) {
+				t.Errorf("Motifs(%s) = %v, want it to contain %v", tt.move, got, tt.want)
+			}
+			if fen := b.ExportFEN(); fen != tt.fen {
+				t.Errorf("board after Motifs = %s, want %s", fen, tt.fen)
+			}
+		})
+	}
+}
@@ -62,3 +62,13 @@ func (m Move) IsPromotion() bool {
 func (m Move) IsSpecial() bool {
 	return m.Flags() != QuietMove
 }
+
+// UCI returns the move in UCI long algebraic notation, e.g. "e2e4" or
+// "e7e8q". Castling is written as the king's move, e.g. "e1g1".
+func (m Move) UCI() string {
+	uci := squareName(m.From()) + squareName(m.To())
+	if m.IsPromotion() {
+		uci += string("nbrq"[m.Flags()&0x3])
+	}
+	return uci
+}
@@ -234,14 +234,14 @@ func (b *Board) GenerateMoves() []Move {
 	offset := b.SideToMove * 6
 
 	for _, move := range pseudoLegalMoves {
-		undo := b.MakeMove(move)
+		undo := b.makeMove(move)
 		king := b.Pieces[offset+5].LSB()
 
 		if !b.IsSqAttacked(king, b.SideToMove) {
 			moves = append(moves, move)
 		}
 
-		b.UnmakeMove(move, undo)
+		b.unmakeMove(move, undo)
 	}
 
 	return moves
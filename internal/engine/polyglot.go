@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+)
+
+// Book is a Polyglot opening book. Entries are kept as in the .bin file,
+// sorted by position key so the moves for a position are found with a
+// binary search.
+type Book struct {
+	entries []bookEntry
+}
+
+// BookMove is a legal move found in the book and its weight, higher weights
+// being played more often.
+type BookMove struct {
+	Move   Move
+	Weight int
+}
+
+// BookEntry is a move played in the position with the given Polyglot key,
+// used to build a book.
+type BookEntry struct {
+	Key    uint64
+	Move   Move
+	Weight uint16
+}
+
+// bookEntry is one 16-byte record of a .bin file. The 32-bit learn field
+// that follows the weight is not used.
+type bookEntry struct {
+	key    uint64
+	move   uint16
+	weight uint16
+}
+
+const bookEntrySize = 16
+
+// Offsets into polyglotRandom
+const (
+	polyglotCastle    = 768
+	polyglotEnPassant = 772
+	polyglotTurn      = 780
+)
+
+// PolyglotKey returns the Polyglot hash of the position, which is what book
+// entries are keyed by. It differs from ZobristKey: the keys are the fixed
+// Random64 table, the en passant file only counts if a pawn of the side to
+// move stands next to the double pushed pawn, and the turn key is for white.
+func PolyglotKey(b *Board) uint64 {
+	var key uint64
+	for i := range 12 {
+		// Polyglot orders pieces black pawn, white pawn, black knight, ...
+		kind := 2 * (i % 6)
+		if i < BlackPawn {
+			kind++
+		}
+
+		pieces := b.Pieces[i]
+		for pieces != 0 {
+			key ^= polyglotRandom[64*kind+pieces.PopLSB()]
+		}
+	}
+
+	// CastleRights has the same bit order as the Polyglot castling keys
+	for i := range 4 {
+		if b.CastleRights&(1<<i) != 0 {
+			key ^= polyglotRandom[polyglotCastle+i]
+		}
+	}
+
+	if b.EnPassant != -1 {
+		pawns := b.Pieces[WhitePawn]
+		if b.SideToMove == Black {
+			pawns = b.Pieces[BlackPawn]
+		}
+		if PawnAttacks(b.EnPassant, b.SideToMove^1)&pawns != 0 {
+			key ^= polyglotRandom[polyglotEnPassant+b.EnPassant%8]
+		}
+	}
+
+	if b.SideToMove == White {
+		key ^= polyglotRandom[polyglotTurn]
+	}
+
+	return key
+}
+
+// LoadBook reads a Polyglot .bin book.
+func LoadBook(path string) (*Book, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%bookEntrySize != 0 {
+		return nil, fmt.Errorf("book %s: size %d is not a multiple of %d", path, len(data), bookEntrySize)
+	}
+
+	bk := &Book{entries: make([]bookEntry, len(data)/bookEntrySize)}
+	for i := range bk.entries {
+		// Entries are big-endian: key, move, weight, learn
+		rec := data[i*bookEntrySize:]
+		bk.entries[i] = bookEntry{
+			key:    binary.BigEndian.Uint64(rec),
+			move:   binary.BigEndian.Uint16(rec[8:]),
+			weight: binary.BigEndian.Uint16(rec[10:]),
+		}
+		if i > 0 && bk.entries[i].key < bk.entries[i-1].key {
+			return nil, fmt.Errorf("book %s: entries are not sorted by key", path)
+		}
+	}
+
+	return bk, nil
+}
+
+// NewBook returns a book of the entries, sorted by key and then by weight,
+// highest first, as Polyglot books are.
+func NewBook(entries []BookEntry) *Book {
+	bk := &Book{entries: make([]bookEntry, len(entries))}
+	for i, e := range entries {
+		bk.entries[i] = bookEntry{key: e.Key, move: polyglotEncode(e.Move), weight: e.Weight}
+	}
+
+	slices.SortStableFunc(bk.entries, func(a, b bookEntry) int {
+		return cmp.Or(cmp.Compare(a.key, b.key), cmp.Compare(b.weight, a.weight))
+	})
+	return bk
+}
+
+// Save writes the book to path as a Polyglot .bin file, with the learn
+// field of every entry set to 0.
+func (bk *Book) Save(path string) error {
+	data := make([]byte, 0, len(bk.entries)*bookEntrySize)
+	for _, e := range bk.entries {
+		data = binary.BigEndian.AppendUint64(data, e.key)
+		data = binary.BigEndian.AppendUint16(data, e.move)
+		data = binary.BigEndian.AppendUint16(data, e.weight)
+		data = binary.BigEndian.AppendUint32(data, 0)
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Len returns the number of entries in the book.
+func (bk *Book) Len() int {
+	return len(bk.entries)
+}
+
+// Moves returns the book moves for the position, highest weight first.
+// Entries that are not legal in the position, which happens with hash
+// collisions, are skipped.
+func (bk *Book) Moves(b *Board) []BookMove {
+	key := PolyglotKey(b)
+	i, _ := slices.BinarySearchFunc(bk.entries, key, func(e bookEntry, key uint64) int {
+		return cmp.Compare(e.key, key)
+	})
+
+	var moves []BookMove
+	legal := b.GenerateMoves()
+	for ; i < len(bk.entries) && bk.entries[i].key == key; i++ {
+		if m, ok := polyglotMove(b, legal, bk.entries[i].move); ok {
+			moves = append(moves, BookMove{Move: m, Weight: int(bk.entries[i].weight)})
+		}
+	}
+
+	slices.SortStableFunc(moves, func(a, b BookMove) int {
+		return b.Weight - a.Weight
+	})
+	return moves
+}
+
+// BestMove returns the book move with the highest weight, or false if the
+// position is not in the book.
+func (bk *Book) BestMove(b *Board) (Move, bool) {
+	moves := bk.Moves(b)
+	if len(moves) == 0 {
+		return 0, false
+	}
+	return moves[0].Move, true
+}
+
+// RandomMove picks a book move at random in proportion to the weights, or
+// returns false if the position is not in the book. Moves with weight 0 are
+// never played.
+func (bk *Book) RandomMove(b *Board) (Move, bool) {
+	moves := bk.Moves(b)
+
+	total := 0
+	for _, bm := range moves {
+		total += bm.Weight
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	r := rand.IntN(total)
+	for _, bm := range moves {
+		if r < bm.Weight {
+			return bm.Move, true
+		}
+		r -= bm.Weight
+	}
+
+	return 0, false
+}
+
+// polyglotMove finds the legal move matching a Polyglot move: to file in
+// bits 0-2, to rank in bits 3-5, from file and rank in bits 6-11 and the
+// promotion piece (1 knight to 4 queen) in bits 12-14. Castling is written
+// as the king taking its own rook, e.g. e1h1 for e1g1.
+func polyglotMove(b *Board, legal []Move, pm uint16) (Move, bool) {
+	to := int(pm & 0x3f)
+	from := int(pm>>6) & 0x3f
+	promotion := int(pm>>12) & 0x7
+
+	king := b.Pieces[WhiteKing]
+	if b.SideToMove == Black {
+		king = b.Pieces[BlackKing]
+	}
+	if king.Occupied(from) && (from == 4 || from == 60) {
+		switch to - from {
+		case 3:
+			to = from + 2
+		case -4:
+			to = from - 2
+		}
+	}
+
+	for _, m := range legal {
+		if m.From() != from || m.To() != to || m.IsPromotion() != (promotion != 0) {
+			continue
+		}
+		// Promotion flags order the pieces knight, bishop, rook, queen too
+		if promotion != 0 && m.Flags()&0x3 != promotion-1 {
+			continue
+		}
+		return m, true
+	}
+
+	return 0, false
+}
+
+// polyglotEncode returns the Polyglot encoding of a move, see polyglotMove.
+func polyglotEncode(m Move) uint16 {
+	from, to := m.From(), m.To()
+	switch m.Flags() {
+	case KCastle:
+		to = from + 3
+	case QCastle:
+		to = from - 4
+	}
+
+	pm := uint16(from<<6 | to)
+	if m.IsPromotion() {
+		pm |= uint16(m.Flags()&0x3+1) << 12
+	}
+	return pm
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"cmp"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPolyglotKey(t *testing.T) {
+	// Test positions and keys from the Polyglot book format description
+	tests := []struct {
+		moves string
+		key   uint64
+	}{
+		{"", 0x463b96181691fc9c},
+		{"e2e4", 0x823c9b50fd114196},
+		{"e2e4 d7d5", 0x0756b94461c50fb0},
+		{"e2e4 d7d5 e4e5", 0x662fafb965db29d4},
+		{"e2e4 d7d5 e4e5 f7f5", 0x22a48b5a8e47ff78},
+		{"e2e4 d7d5 e4e5 f7f5 e1e2", 0x652a607ca3f242c1},
+		{"e2e4 d7d5 e4e5 f7f5 e1e2 e8f7", 0x00fdd303c946bdd9},
+		{"a2a4 b7b5 h2h4 b5b4 c2c4", 0x3c8123ea7b067637},
+		{"a2a4 b7b5 h2h4 b5b4 c2c4 b4c3 a1a3", 0x5c3f9b829b279560},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		b := InitBoard("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		for _, uci := range strings.Fields(tt.moves) {
+			m, err := b.ParseUCI(uci)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.moves, err)
+			}
+			b.MakeMove(m)
+		}
+
+		if got := PolyglotKey(b); got != tt.key {
+			t.Errorf("PolyglotKey after %q = %016x, want %016x", tt.moves, got, tt.key)
+		}
+	}
+}
+
+// writeBook writes entries as a .bin book with the keys and moves given in
+// the Polyglot encoding.
+func writeBook(t *testing.T, entries []bookEntry) string {
+	data := make([]byte, 0, len(entries)*bookEntrySize)
+	for _, e := range entries {
+		data = binary.BigEndian.AppendUint64(data, e.key)
+		data = binary.BigEndian.AppendUint16(data, e.move)
+		data = binary.BigEndian.AppendUint16(data, e.weight)
+		data = binary.BigEndian.AppendUint32(data, 0)
+	}
+
+	path := filepath.Join(t.TempDir(), "book.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// polyglotEncode returns the Polyglot encoding of a move between squares
+// with an optional promotion piece, 1 for a knight up to 4 for a queen.
+func rawPolyglotMove(from, to, promotion int) uint16 {
+	return uint16(promotion<<12 | from<<6 | to)
+}
+
+func TestBook(t *testing.T) {
+	Init("")
+	start := InitBoard("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	castle := InitBoard("r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1")
+	promote := InitBoard("1n5k/P7/8/8/8/8/8/K7 w - - 0 1")
+
+	entries := []bookEntry{
+		{PolyglotKey(start), rawPolyglotMove(12, 28, 0), 10},
+		{PolyglotKey(start), rawPolyglotMove(11, 27, 0), 30},
+		{PolyglotKey(start), rawPolyglotMove(6, 21, 0), 0},
+		// Not legal in the position, as after a hash collision
+		{PolyglotKey(start), rawPolyglotMove(4, 36, 0), 50},
+		{PolyglotKey(castle), rawPolyglotMove(60, 63, 0), 2},
+		{PolyglotKey(castle), rawPolyglotMove(60, 56, 0), 1},
+		{PolyglotKey(promote), rawPolyglotMove(48, 57, 1), 1},
+		{PolyglotKey(promote), rawPolyglotMove(48, 57, 4), 3},
+		{PolyglotKey(promote), rawPolyglotMove(48, 56, 2), 1},
+	}
+	slices.SortStableFunc(entries, func(a, b bookEntry) int {
+		return cmp.Compare(a.key, b.key)
+	})
+
+	bk, err := LoadBook(writeBook(t, entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bk.Len() != len(entries) {
+		t.Errorf("Len = %d, want %d", bk.Len(), len(entries))
+	}
+
+	tests := []struct {
+		name  string
+		b     *Board
+		moves string
+	}{
+		{"weights", start, "d2d4:30 e2e4:10 g1f3:0"},
+		{"castling as king takes rook", castle, "e8g8:2 e8c8:1"},
+		{"promotion pieces", promote, "a7b8q:3 a7b8n:1 a7a8b:1"},
+		{"out of book", InitBoard("4k3/8/8/8/8/8/8/4K3 w - - 0 1"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, bm := range bk.Moves(tt.b) {
+				got = append(got, bm.Move.UCI()+":"+strconv.Itoa(bm.Weight))
+			}
+			if s := strings.Join(got, " "); s != tt.moves {
+				t.Errorf("Moves = %q, want %q", s, tt.moves)
+			}
+		})
+	}
+
+	if m, ok := bk.BestMove(start); !ok || m.UCI() != "d2d4" {
+		t.Errorf("BestMove = %s, %v, want d2d4", m.UCI(), ok)
+	}
+	for range 100 {
+		m, ok := bk.RandomMove(start)
+		if !ok || (m.UCI() != "d2d4" && m.UCI() != "e2e4") {
+			t.Fatalf("RandomMove = %s, %v, want d2d4 or e2e4", m.UCI(), ok)
+		}
+	}
+	if _, ok := bk.RandomMove(InitBoard("4k3/8/8/8/8/8/8/4K3 w - - 0 1")); ok {
+		t.Error("RandomMove out of book returned a move")
+	}
+}
+
+func TestLoadBookErrors(t *testing.T) {
+	unsorted := writeBook(t, []bookEntry{{key: 2}, {key: 1}})
+	if _, err := LoadBook(unsorted); err == nil || !strings.Contains(err.Error(), "not sorted") {
+		t.Errorf("LoadBook(unsorted) error = %v", err)
+	}
+
+	truncated := filepath.Join(t.TempDir(), "truncated.bin")
+	if err := os.WriteFile(truncated, make([]byte, 20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBook(truncated); err == nil || !strings.Contains(err.Error(), "multiple of 16") {
+		t.Errorf("LoadBook(truncated) error = %v", err)
+	}
+}
+
+func TestBookSaveLoad(t *testing.T) {
+	Init("")
+	castle := InitBoard("r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1")
+	promote := InitBoard("1n5k/P7/8/8/8/8/8/K7 w - - 0 1")
+
+	var entries []BookEntry
+	for _, e := range []struct {
+		b      *Board
+		uci    string
+		weight uint16
+	}{
+		{castle, "e8g8", 1},
+		{castle, "e8c8", 5},
+		{promote, "a7b8r", 2},
+		{promote, "a7a8q", 7},
+	} {
+		m, err := e.b.ParseUCI(e.uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, BookEntry{Key: PolyglotKey(e.b), Move: m, Weight: e.weight})
+	}
+
+	path := filepath.Join(t.TempDir(), "book.bin")
+	if err := NewBook(entries).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	bk, err := LoadBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		b     *Board
+		moves string
+	}{
+		{castle, "e8c8:5 e8g8:1"},
+		{promote, "a7a8q:7 a7b8r:2"},
+	} {
+		var got []string
+		for _, bm := range bk.Moves(tt.b) {
+			got = append(got, bm.Move.UCI()+":"+strconv.Itoa(bm.Weight))
+		}
+		if s := strings.Join(got, " "); s != tt.moves {
+			t.Errorf("%s: Moves = %q, want %q", tt.b.ExportFEN(), s, tt.moves)
+		}
+	}
+
+	// The castling moves are stored as the king taking its own rook
+	if got, want := polyglotEncode(entries[0].Move), rawPolyglotMove(60, 63, 0); got != want {
+		t.Errorf("encoding of e8g8 = %#x, want %#x", got, want)
+	}
+}
@@ -0,0 +1,372 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Problem stipulations. In direct play (Directmate, Selfmate, Stalemate)
+// the side to move is the solving side and the other side defends. In help
+// play (Helpmate, Helpstalemate) both sides cooperate, the side to move
+// starts and is the one mated or stalemated by the other side's last move.
+const (
+	Directmate int = iota
+	Helpmate
+	Selfmate
+	Stalemate
+	Helpstalemate
+)
+
+var stipulationPrefixes = [...]string{"#", "h#", "s#", "=", "h="}
+
+// Stipulation is a problem goal and the number of moves to reach it in.
+type Stipulation struct {
+	Kind  int
+	Moves int
+}
+
+// ParseStipulation parses problem notation such as "#3", "h#2", "s#2", "=3"
+// or "h=2".
+func ParseStipulation(s string) (Stipulation, error) {
+	s = strings.TrimSpace(s)
+	// Try longer prefixes first so "h#" is not read as "#"
+	for _, kind := range []int{Helpmate, Selfmate, Helpstalemate, Directmate, Stalemate} {
+		prefix := stipulationPrefixes[kind]
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+
+		moves, err := strconv.Atoi(s[len(prefix):])
+		if err != nil || moves < 1 {
+			return Stipulation{}, fmt.Errorf("invalid stipulation %q: expected a positive number of moves", s)
+		}
+		return Stipulation{Kind: kind, Moves: moves}, nil
+	}
+
+	return Stipulation{}, fmt.Errorf("invalid stipulation %q: expected #, h#, s#, = or h= followed by moves", s)
+}
+
+func (st Stipulation) String() string {
+	return stipulationPrefixes[st.Kind] + strconv.Itoa(st.Moves)
+}
+
+// help returns true for stipulations where both sides cooperate.
+func (st Stipulation) help() bool {
+	return st.Kind == Helpmate || st.Kind == Helpstalemate
+}
+
+// ProblemNode is a move in a problem solution. In direct play a solving
+// move lists every defence as replies, and each defence lists every
+// continuation that still solves. In help play replies are the moves that
+// continue towards the goal. A move without replies reaches the goal.
+type ProblemNode struct {
+	Move    Move
+	SAN     string
+	Replies []*ProblemNode
+}
+
+// ProblemResult holds every solution of a problem.
+type ProblemResult struct {
+	Stipulation string
+	// First moves of every solution, empty if the problem has none
+	Solutions []*ProblemNode
+	// More than one solution, or a solution in fewer moves than stated.
+	// In help play alternatives on the final move are duals, not cooks.
+	Cooked bool
+	// Points where a solution can be continued in more than one way,
+	// in problem notation with the alternatives separated by "/"
+	Duals []string
+	// The solutions in problem notation, one variation per line
+	Notation []string
+}
+
+// problemKey identifies a position with a number of moves or plies left.
+type problemKey struct {
+	hash  uint64
+	depth int
+}
+
+// problemSolver enumerates problem solutions with a depth-first search,
+// caching positions that were shown to have no solution.
+type problemSolver struct {
+	b      *Board
+	st     Stipulation
+	proven map[problemKey]bool
+}
+
+// SolveProblem finds every solution of the stipulation for the position,
+// along with cooks and duals.
+func (b *Board) SolveProblem(st Stipulation) *ProblemResult {
+	s := &problemSolver{
+		b:      b,
+		st:     st,
+		proven: make(map[problemKey]bool),
+	}
+
+	result := &ProblemResult{Stipulation: st.String()}
+	if st.help() {
+		result.Solutions = s.helpNodes(2 * st.Moves)
+		result.Cooked = s.helpLines(result.Solutions) > 1
+		// A shorter help line only cooks a problem that has a solution
+		for moves := 1; moves < st.Moves && len(result.Solutions) > 0 && !result.Cooked; moves++ {
+			result.Cooked = len(s.helpNodes(2*moves)) > 0
+		}
+	} else {
+		result.Solutions = s.attackNodes(st.Moves)
+		result.Cooked = len(result.Solutions) > 1
+		for moves := 1; moves < st.Moves && !result.Cooked; moves++ {
+			result.Cooked = s.attack(moves)
+		}
+	}
+
+	result.Duals = s.duals(result.Solutions, nil)
+	result.Notation = s.notation(result.Solutions)
+
+	return result
+}
+
+// attack returns true if the solving side to move reaches the goal within
+// n moves against any defence.
+func (s *problemSolver) attack(n int) bool {
+	if n <= 0 {
+		return false
+	}
+
+	key := problemKey{s.b.ZobristKey(), n}
+	if solved, ok := s.proven[key]; ok {
+		return solved
+	}
+
+	solved := false
+	for _, m := range s.b.GenerateMoves() {
+		undo := s.b.makeMove(m)
+		solved = s.defend(n)
+		s.b.unmakeMove(m, undo)
+
+		if solved {
+			break
+		}
+	}
+
+	s.proven[key] = solved
+	return solved
+}
+
+// defend returns true if every move of the defending side to move still
+// lets the solving side reach the goal, with n moves counted from the
+// solving side's last move.
+func (s *problemSolver) defend(n int) bool {
+	moves := s.b.GenerateMoves()
+	if len(moves) == 0 {
+		switch s.st.Kind {
+		case Directmate:
+			return s.b.IsCheckmate()
+		case Stalemate:
+			return s.b.IsStalemate()
+		default:
+			return false
+		}
+	}
+
+	// Only a selfmate can finish on the defending side's move
+	if n == 1 && s.st.Kind != Selfmate {
+		return false
+	}
+
+	for _, m := range moves {
+		undo := s.b.makeMove(m)
+		solved := s.solvedByDefence() || s.attack(n-1)
+		s.b.unmakeMove(m, undo)
+
+		if !solved {
+			return false
+		}
+	}
+
+	return true
+}
+
+// solvedByDefence returns true if the defending side's last move reached
+// the goal, which is only possible in a selfmate.
+func (s *problemSolver) solvedByDefence() bool {
+	return s.st.Kind == Selfmate && s.b.IsCheckmate()
+}
+
+// attackNodes returns every solving move that reaches the goal within n
+// moves, with the defences below them.
+func (s *problemSolver) attackNodes(n int) []*ProblemNode {
+	if n <= 0 {
+		return nil
+	}
+
+	var nodes []*ProblemNode
+	for _, m := range s.b.GenerateMoves() {
+		undo := s.b.makeMove(m)
+		solved := s.defend(n)
+		s.b.unmakeMove(m, undo)
+
+		if !solved {
+			continue
+		}
+
+		node := &ProblemNode{Move: m, SAN: s.b.SAN(m)}
+		undo = s.b.makeMove(m)
+		for _, reply := range s.b.GenerateMoves() {
+			defence := &ProblemNode{Move: reply, SAN: s.b.SAN(reply)}
+
+			undo := s.b.makeMove(reply)
+			if !s.solvedByDefence() {
+				defence.Replies = s.attackNodes(n - 1)
+			}
+			s.b.unmakeMove(reply, undo)
+
+			node.Replies = append(node.Replies, defence)
+		}
+		s.b.unmakeMove(m, undo)
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// helpNodes returns every move of the side to move that continues a help
+// line reaching the goal after exactly plies more plies.
+func (s *problemSolver) helpNodes(plies int) []*ProblemNode {
+	key := problemKey{s.b.ZobristKey(), plies}
+	if solved, ok := s.proven[key]; ok && !solved {
+		return nil
+	}
+
+	var nodes []*ProblemNode
+	for _, m := range s.b.GenerateMoves() {
+		undo := s.b.makeMove(m)
+		var replies []*ProblemNode
+		solved := false
+		if plies == 1 {
+			if s.st.Kind == Helpmate {
+				solved = s.b.IsCheckmate()
+			} else {
+				solved = s.b.IsStalemate()
+			}
+		} else {
+			replies = s.helpNodes(plies - 1)
+			solved = len(replies) > 0
+		}
+		s.b.unmakeMove(m, undo)
+
+		if solved {
+			nodes = append(nodes, &ProblemNode{Move: m, SAN: s.b.SAN(m), Replies: replies})
+		}
+	}
+
+	s.proven[key] = len(nodes) > 0
+	return nodes
+}
+
+// helpLines counts the help play solutions, treating alternatives on the
+// final move as one solution.
+func (s *problemSolver) helpLines(nodes []*ProblemNode) int {
+	count := 0
+	for _, node := range nodes {
+		switch {
+		case len(node.Replies) == 0:
+			// Only reached for help play in one ply
+			count++
+		case len(node.Replies[0].Replies) == 0:
+			count++
+		default:
+			count += s.helpLines(node.Replies)
+		}
+	}
+	return count
+}
+
+// duals returns every point in the solution tree where the side that has to
+// find a move has more than one good one, excluding the first move.
+func (s *problemSolver) duals(nodes []*ProblemNode, line []*ProblemNode) []string {
+	var duals []string
+	for _, node := range nodes {
+		path := append(line[:len(line):len(line)], node)
+
+		var choices []*ProblemNode
+		if s.st.help() {
+			// Alternatives on the final move of a help line
+			if len(node.Replies) > 1 && len(node.Replies[0].Replies) == 0 {
+				choices = node.Replies
+			}
+		} else if len(path)%2 == 0 && len(node.Replies) > 1 {
+			// Defences answered by more than one continuation
+			choices = node.Replies
+		}
+
+		if choices != nil {
+			sans := make([]string, len(choices))
+			for i, choice := range choices {
+				sans[i] = choice.SAN
+			}
+			next := append(path, &ProblemNode{SAN: strings.Join(sans, "/")})
+			duals = append(duals, s.lineNotation(next, 0))
+		}
+
+		duals = append(duals, s.duals(node.Replies, path)...)
+	}
+	return duals
+}
+
+// notation returns every variation of the solutions, the first in full and
+// the rest starting where they leave the previous one.
+func (s *problemSolver) notation(nodes []*ProblemNode) []string {
+	var paths [][]*ProblemNode
+	var walk func(nodes []*ProblemNode, line []*ProblemNode)
+	walk = func(nodes []*ProblemNode, line []*ProblemNode) {
+		for _, node := range nodes {
+			path := append(line[:len(line):len(line)], node)
+			if len(node.Replies) == 0 {
+				paths = append(paths, path)
+			}
+			walk(node.Replies, path)
+		}
+	}
+	walk(nodes, nil)
+
+	lines := make([]string, len(paths))
+	var previous []*ProblemNode
+	for i, path := range paths {
+		start := 0
+		for start < len(previous) && start < len(path)-1 && previous[start] == path[start] {
+			start++
+		}
+		lines[i] = s.lineNotation(path, start)
+		previous = path
+	}
+
+	return lines
+}
+
+// lineNotation writes the moves of line from ply start in problem notation:
+// moves by the side that starts are numbered "1." and a line starting with
+// the other side's move is numbered "1...". Solving first moves in direct
+// play are marked with "!".
+func (s *problemSolver) lineNotation(line []*ProblemNode, start int) string {
+	text := strings.Builder{}
+	for ply := start; ply < len(line); ply++ {
+		if ply > start {
+			text.WriteByte(' ')
+		}
+
+		number := ply/2 + 1
+		if ply%2 == 0 {
+			text.WriteString(strconv.Itoa(number) + ".")
+		} else if ply == start {
+			text.WriteString(strconv.Itoa(number) + "...")
+		}
+
+		text.WriteString(line[ply].SAN)
+		if ply == 0 && !s.st.help() {
+			text.WriteByte('!')
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseStipulation(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Stipulation
+	}{
+		{"#2", Stipulation{Directmate, 2}},
+		{"h#3", Stipulation{Helpmate, 3}},
+		{"s#1", Stipulation{Selfmate, 1}},
+		{"=2", Stipulation{Stalemate, 2}},
+		{" h=4 ", Stipulation{Helpstalemate, 4}},
+	}
+	for _, tt := range tests {
+		got, err := ParseStipulation(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseStipulation(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "#", "#0", "x#2", "h#two"} {
+		if _, err := ParseStipulation(in); err == nil {
+			t.Errorf("ParseStipulation(%q) returned no error", in)
+		}
+	}
+}
+
+func TestSolveProblem(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		stipulation string
+		notation    []string
+		cooked      bool
+		duals       []string
+	}{
+		{
+			"helpmate in one", "7k/8/6K1/8/8/8/8/R7 b - - 0 1", "h#1",
+			[]string{"1.Kg8 Ra8#"}, false, nil,
+		},
+		{
+			"helpmate in two", "3Qk3/8/B7/8/8/8/8/1K6 b - - 0 1", "h#2",
+			[]string{"1.Kf7 Qf6+ 2.Ke8 Bb5#"}, false, nil,
+		},
+		{
+			// 1.Ke8 Rg8# solves in one move
+			"helpmate with a shorter solution", "5k2/8/4K3/6R1/8/8/8/8 b - - 0 1", "h#2",
+			[]string{"1.Ke8 Kd6 2.Kd8 Rg8#"}, true, nil,
+		},
+		{
+			"directmate with two keys", "k7/8/2K5/8/8/8/8/7R w - - 0 1", "#2",
+			[]string{"1.Kb6! Kb8 2.Rh8#", "1.Kc7! Ka7 2.Ra1#"}, true, nil,
+		},
+		{
+			"directmate with a dual", "8/8/3R4/8/8/5k2/R7/3R2K1 w - - 0 1", "#2",
+			[]string{"1.R6d4! Ke3 2.R1d3#", "1...Kg3 2.R1d3#", "2.Ra3#"}, false,
+			[]string{"1.R6d4! Kg3 2.R1d3#/Ra3#"},
+		},
+		{
+			"selfmate in one", "2n3qk/Q7/5n1K/8/8/8/8/8 w - - 0 1", "s#1",
+			[]string{"1.Qg7+! Qxg7#"}, false, nil,
+		},
+		{
+			"stalemate in one", "k7/8/8/1Q6/8/8/8/K7 w - - 0 1", "=1",
+			[]string{"1.Qb6!"}, false, nil,
+		},
+		{
+			"no solution", "k7/8/8/8/8/8/8/K6R w - - 0 1", "#1",
+			nil, false, nil,
+		},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := ParseStipulation(tt.stipulation)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := InitBoard(tt.fen)
+			result := b.SolveProblem(st)
+			if result.Stipulation != tt.stipulation {
+				t.Errorf("Stipulation = %q, want %q", result.Stipulation, tt.stipulation)
+			}
+			if !slices.Equal(result.Notation, tt.notation) {
+				t.Errorf("Notation = %q, want %q", result.Notation, tt.notation)
+			}
+			if result.Cooked != tt.cooked {
+				t.Errorf("Cooked = %v, want %v", result.Cooked, tt.cooked)
+			}
+			if !slices.Equal(result.Duals, tt.duals) {
+				t.Errorf("Duals = %q, want %q", result.Duals, tt.duals)
+			}
+		})
+	}
+}
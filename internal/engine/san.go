@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// squareName returns the algebraic name of the square, e.g. "e4".
+func squareName(sq int) string {
+	return string([]byte{byte('a' + sq%8), byte('1' + sq/8)})
+}
+
+// SAN returns the legal move in standard algebraic notation for the current
+// position, e.g. "Nbd7", "exd5", "e8=Q+" or "O-O#".
+func (b *Board) SAN(m Move) string {
+	from, to, flags := m.From(), m.To(), m.Flags()
+	san := strings.Builder{}
+
+	switch {
+	case flags == KCastle:
+		san.WriteString("O-O")
+	case flags == QCastle:
+		san.WriteString("O-O-O")
+	default:
+		piece := b.pieceAt(from)
+		if piece%6 == WhitePawn {
+			if m.IsCapture() {
+				san.WriteByte(byte('a' + from%8))
+				san.WriteByte('x')
+			}
+			san.WriteString(squareName(to))
+			if m.IsPromotion() {
+				san.WriteByte('=')
+				san.WriteByte("NBRQ"[flags&0x3])
+			}
+			break
+		}
+
+		san.WriteByte("PNBRQK"[piece%6])
+
+		// Disambiguate between pieces of the same type reaching the square,
+		// by file if that is enough, then by rank, then by both
+		ambiguous, sameFile, sameRank := false, false, false
+		for _, other := range b.GenerateMoves() {
+			if other.To() != to || other.From() == from || b.pieceAt(other.From()) != piece {
+				continue
+			}
+			ambiguous = true
+			sameFile = sameFile || other.From()%8 == from%8
+			sameRank = sameRank || other.From()/8 == from/8
+		}
+		if ambiguous {
+			if !sameFile {
+				san.WriteByte(byte('a' + from%8))
+			} else if !sameRank {
+				san.WriteByte(byte('1' + from/8))
+			} else {
+				san.WriteString(squareName(from))
+			}
+		}
+
+		if m.IsCapture() {
+			san.WriteByte('x')
+		}
+		san.WriteString(squareName(to))
+	}
+
+	undo := b.makeMove(m)
+	if b.IsInCheck() {
+		if len(b.GenerateMoves()) == 0 {
+			san.WriteByte('#')
+		} else {
+			san.WriteByte('+')
+		}
+	}
+	b.unmakeMove(m, undo)
+
+	return san.String()
+}
+
+// ParseUCI returns the legal move in the current position written in UCI
+// long algebraic notation, e.g. "e2e4", "e1g1" or "e7e8q".
+func (b *Board) ParseUCI(uci string) (Move, error) {
+	for _, m := range b.GenerateMoves() {
+		if m.UCI() == uci {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid move %q: not legal in the position", uci)
+}
+
+// ParseSAN returns the legal move in the current position written in
+// standard algebraic notation, e.g. "Nbd7", "exd5", "e8=Q+" or "O-O". Check
+// and annotation marks, zeros for castling, a missing "x" or "=" and more
+// disambiguation than needed are accepted, as found in PGN files.
+func (b *Board) ParseSAN(san string) (Move, error) {
+	s := strings.TrimRight(strings.TrimSuffix(san, "e.p."), "+#!? ")
+	s = strings.ReplaceAll(s, "0", "O")
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid move %q", san)
+	}
+
+	castle := -1
+	switch s {
+	case "O-O":
+		castle = KCastle
+	case "O-O-O":
+		castle = QCastle
+	}
+
+	piece, promotion := WhitePawn, -1
+	from := s
+	if castle == -1 {
+		if i := strings.IndexByte("NBRQK", s[0]); i != -1 {
+			piece = WhiteKnight + i
+			from = s[1:]
+		}
+		if i := strings.IndexByte("NBRQ", from[len(from)-1]); i != -1 && piece == WhitePawn {
+			promotion = i
+			from = strings.TrimSuffix(from[:len(from)-1], "=")
+		}
+		if len(from) < 2 {
+			return 0, fmt.Errorf("invalid move %q", san)
+		}
+		from = strings.ReplaceAll(from, "x", "")
+	}
+
+	var found Move
+	matches := 0
+	for _, m := range b.GenerateMoves() {
+		if castle != -1 {
+			if m.Flags() != castle {
+				continue
+			}
+		} else if !sanMatches(m, b.pieceAt(m.From())%6, piece, promotion, from) {
+			continue
+		}
+		found = m
+		matches++
+	}
+
+	switch matches {
+	case 0:
+		return 0, fmt.Errorf("invalid move %q: not legal in the position", san)
+	case 1:
+		return found, nil
+	default:
+		return 0, fmt.Errorf("invalid move %q: ambiguous in the position", san)
+	}
+}
+
+// sanMatches reports whether m is the move of piece type described by a SAN
+// move's optional from file and rank followed by its to square, e.g. "bd7".
+func sanMatches(m Move, moved, piece, promotion int, squares string) bool {
+	to := squares[len(squares)-2:]
+	if moved != piece || squareName(m.To()) != to {
+		return false
+	}
+	if m.IsPromotion() != (promotion != -1) || (promotion != -1 && m.Flags()&0x3 != promotion) {
+		return false
+	}
+
+	for _, c := range squares[:len(squares)-2] {
+		switch {
+		case c >= 'a' && c <= 'h':
+			if m.From()%8 != int(c-'a') {
+				return false
+			}
+		case c >= '1' && c <= '8':
+			if m.From()/8 != int(c-'1') {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,75 @@
+package engine
+
+import "testing"
+
+func TestParseSAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		san  string
+		want string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e4", "e2e4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nf3", "g1f3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Ng1f3", "g1f3"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "exd5", "e4d5"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "ed5", "e4d5"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "O-O", "e1g1"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "0-0-0", "e1c1"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "Rd1+", "a1d1"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "Rhf1", "h1f1"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6 e.p.", "e5d6"},
+		{"1n5k/P7/8/8/8/8/8/K7 w - - 0 1", "axb8=N", "a7b8n"},
+		{"1n5k/P7/8/8/8/8/8/K7 w - - 0 1", "a8Q+!", "a7a8q"},
+		{"k7/8/8/8/8/8/8/R3R2K w - - 0 1", "R1e8#", "e1e8"},
+		{"k7/8/8/3N4/8/8/8/3N3K w - - 0 1", "N1c3", "d1c3"},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		b := InitBoard(tt.fen)
+		m, err := b.ParseSAN(tt.san)
+		if err != nil {
+			t.Errorf("%s: ParseSAN(%q): %v", tt.fen, tt.san, err)
+			continue
+		}
+		if m.UCI() != tt.want {
+			t.Errorf("%s: ParseSAN(%q) = %s, want %s", tt.fen, tt.san, m.UCI(), tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		fen string
+		san string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e5"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "O-O"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "x"},
+		{"k7/8/8/8/8/8/8/R3R2K w - - 0 1", "Rb1"},
+		{"k7/8/8/8/8/8/8/R6K w - - 0 1", "Qa2"},
+	} {
+		b := InitBoard(tt.fen)
+		if m, err := b.ParseSAN(tt.san); err == nil {
+			t.Errorf("%s: ParseSAN(%q) = %s, want an error", tt.fen, tt.san, m.UCI())
+		}
+	}
+}
+
+// Every legal move must parse back from its own SAN.
+func TestParseSANRoundTrip(t *testing.T) {
+	Init("")
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+	} {
+		b := InitBoard(fen)
+		for _, m := range b.GenerateMoves() {
+			san := b.SAN(m)
+			got, err := b.ParseSAN(san)
+			if err != nil || got != m {
+				t.Errorf("%s: ParseSAN(%q) = %s, %v, want %s", fen, san, got.UCI(), err, m.UCI())
+			}
+		}
+	}
+}
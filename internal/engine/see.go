@@ -0,0 +1,178 @@
+package engine
+
+// PieceValues holds the material value of each piece type in centipawns,
+// indexed by piece index modulo 6 (Pawn, Knight, Bishop, Rook, Queen, King).
+// The king is given a value large enough that trading it is never profitable.
+var PieceValues = [6]int{100, 320, 330, 500, 900, 20000}
+
+// pieceAt returns the index of the piece on the square in Board.Pieces,
+// or -1 if the square is empty.
+func (b *Board) pieceAt(sq int) int {
+	for i := range 12 {
+		if b.Pieces[i].Occupied(sq) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AttackersTo returns a bitboard of every piece of both colors that attacks
+// the square given the occupancy. Sliders are computed against the occupancy,
+// so removing a piece from it reveals the x-ray attackers behind it.
+// Pieces not in the occupancy are never returned as attackers.
+func (b *Board) AttackersTo(sq int, occupancy Bitboard) Bitboard {
+	diagonal := b.Pieces[WhiteBishop] | b.Pieces[BlackBishop] | b.Pieces[WhiteQueen] | b.Pieces[BlackQueen]
+	straight := b.Pieces[WhiteRook] | b.Pieces[BlackRook] | b.Pieces[WhiteQueen] | b.Pieces[BlackQueen]
+
+	// A white pawn attacks sq if it stands on a square a black pawn
+	// on sq would attack, and vice versa.
+	attackers := BlackPawnMoves[sq]&b.Pieces[WhitePawn] |
+		WhitePawnMoves[sq]&b.Pieces[BlackPawn] |
+		KnightMoves[sq]&(b.Pieces[WhiteKnight]|b.Pieces[BlackKnight]) |
+		KingMoves[sq]&(b.Pieces[WhiteKing]|b.Pieces[BlackKing]) |
+		BishopAttacks(sq, occupancy)&diagonal |
+		RookAttacks(sq, occupancy)&straight
+
+	return attackers & occupancy
+}
+
+// leastValuableAttacker returns the square and piece index of the cheapest
+// piece in attackers belonging to color, or -1, -1 if there is none.
+func (b *Board) leastValuableAttacker(attackers Bitboard, color int) (int, int) {
+	offset := color * 6
+	for i := offset; i <= offset+5; i++ {
+		if bb := attackers & b.Pieces[i]; bb != 0 {
+			return bb.LSB(), i
+		}
+	}
+	return -1, -1
+}
+
+// seeSetup returns the value of the piece captured by the move, the value
+// of the piece standing on the target square afterwards, and the occupancy
+// after the move with the moving piece and any en passant pawn removed.
+func (b *Board) seeSetup(m Move) (int, int, Bitboard) {
+	from, to, flags := m.From(), m.To(), m.Flags()
+	occ := b.Occupancy[All]
+	occ.Clear(from)
+
+	captured := 0
+	if flags == EPCapture {
+		captured = PieceValues[WhitePawn]
+		if b.SideToMove == White {
+			occ.Clear(to - 8)
+		} else {
+			occ.Clear(to + 8)
+		}
+	} else if m.IsCapture() {
+		captured = PieceValues[b.pieceAt(to)%6]
+	}
+
+	attacker := PieceValues[b.pieceAt(from)%6]
+	if m.IsPromotion() {
+		promoted := PieceValues[WhiteKnight+flags&0x3]
+		captured += promoted - PieceValues[WhitePawn]
+		attacker = promoted
+	}
+
+	return captured, attacker, occ
+}
+
+// SEE returns the static exchange evaluation of the move in centipawns:
+// the material balance for the side to move once every profitable
+// recapture on the target square has been played out, cheapest attacker
+// first. Pins and promotions during the recapture sequence are ignored.
+// Castling moves always return 0.
+func (b *Board) SEE(m Move) int {
+	if m.Flags() == KCastle || m.Flags() == QCastle {
+		return 0
+	}
+
+	to := m.To()
+	var gain [32]int
+	captured, attacker, occ := b.seeSetup(m)
+	gain[0] = captured
+
+	side := b.SideToMove ^ 1
+	depth := 0
+	for {
+		depth++
+		// Speculative score if the piece on the target square is recaptured
+		gain[depth] = attacker - gain[depth-1]
+
+		sq, piece := b.leastValuableAttacker(b.AttackersTo(to, occ), side)
+		if sq == -1 {
+			break
+		}
+
+		occ.Clear(sq)
+		attacker = PieceValues[piece%6]
+		side ^= 1
+	}
+
+	// Each side may stop capturing when continuing would lose material
+	for depth--; depth > 0; depth-- {
+		gain[depth-1] = -max(-gain[depth-1], gain[depth])
+	}
+
+	return gain[0]
+}
+
+// SEEGreaterOrEqual returns true if the static exchange evaluation of the
+// move is at least threshold. It stops as soon as the outcome is known,
+// which makes it cheaper than comparing the result of SEE.
+func (b *Board) SEEGreaterOrEqual(m Move, threshold int) bool {
+	if m.Flags() == KCastle || m.Flags() == QCastle {
+		return threshold <= 0
+	}
+
+	to := m.To()
+	captured, attacker, occ := b.seeSetup(m)
+
+	swap := captured - threshold
+	if swap < 0 {
+		return false
+	}
+
+	swap = attacker - swap
+	if swap <= 0 {
+		return true
+	}
+
+	side := b.SideToMove
+	// res is 1 while the side that made the move is ahead of the threshold
+	res := 1
+	for {
+		side ^= 1
+		attackers := b.AttackersTo(to, occ)
+		sq, piece := b.leastValuableAttacker(attackers, side)
+		if sq == -1 {
+			break
+		}
+
+		res ^= 1
+
+		// The king may only recapture if the square is no longer defended
+		if piece%6 == WhiteKing {
+			if attackers&b.Occupancy[side^1] != 0 {
+				return res^1 == 1
+			}
+			return res == 1
+		}
+
+		swap = PieceValues[piece%6] - swap
+		if swap < res {
+			break
+		}
+
+		occ.Clear(sq)
+	}
+
+	return res == 1
+}
+
+// IsLosingCapture returns true if the move is a capture that loses
+// material according to static exchange evaluation.
+func (b *Board) IsLosingCapture(m Move) bool {
+	return m.IsCapture() && !b.SEEGreaterOrEqual(m, 0)
+}
@@ -0,0 +1,65 @@
+package engine
+
+import "testing"
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		want int
+	}{
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5", 100},
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "d3e5", -220},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "f3h3", -300},
+	}
+
+	Init("")
+	for _, tt := range tests {
+		b := InitBoard("")
+		if err := b.ParseFEN(tt.fen); err != nil {
+			t.Fatalf("ParseFEN(%q): %v", tt.fen, err)
+		}
+
+		m, err := b.ParseUCI(tt.move)
+		if err != nil {
+			t.Fatalf("ParseUCI(%q): %v", tt.move, err)
+		}
+
+		if got := b.SEE(m); got != tt.want {
+			t.Errorf("%s %s: SEE = %d, want %d", tt.fen, tt.move, got, tt.want)
+		}
+	}
+}
+
+// SEEGreaterOrEqual must agree with SEE for every capture and threshold.
+func TestSEEGreaterOrEqual(t *testing.T) {
+	fens := []string{
+		"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1",
+		"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1",
+		"rnbqkb1r/ppp1pppp/5n2/3p4/4P3/2N5/PPPP1PPP/R1BQKBNR w KQkq - 0 3",
+		"4k3/8/3q4/2b1p3/3P4/2N1N3/8/3QK3 w - - 0 1",
+	}
+
+	Init("")
+	for _, fen := range fens {
+		b := InitBoard("")
+		if err := b.ParseFEN(fen); err != nil {
+			t.Fatalf("ParseFEN(%q): %v", fen, err)
+		}
+
+		for _, m := range b.GenerateMoves() {
+			if !m.IsCapture() {
+				continue
+			}
+
+			see := b.SEE(m)
+			for threshold := -1000; threshold <= 1000; threshold += 10 {
+				if got, want := b.SEEGreaterOrEqual(m, threshold), see >= threshold; got != want {
+					t.Errorf("%s %s: SEEGreaterOrEqual(%d) = %v, SEE = %d", fen, m.UCI(), threshold, got, see)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,738 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Tablebase holds the distance to mate of every placement of a fixed set of
+// pieces with either side to move, built by retrograde analysis. Positions
+// with castling rights or an en passant square are not covered.
+type Tablebase struct {
+	// Material in the form "KQK": the white king and white pieces
+	// followed by the black king and black pieces
+	Material string
+	// Piece indexes in Board.Pieces, in the order squares are packed
+	// into a table index
+	pieces []int
+	// One entry per index, see tbWinValue and tbLossValue
+	values []int16
+}
+
+// Tablebases is a set of tables keyed by their material.
+type Tablebases map[string]*Tablebase
+
+// TBResult is the tablebase verdict for the side to move.
+type TBResult struct {
+	// TBWin, TBDraw or TBLoss
+	Outcome int
+	// Plies until mate with best play from both sides, 0 for draws
+	DTM int
+}
+
+// Outcomes for TBResult.Outcome
+const (
+	TBLoss int = iota - 1
+	TBDraw
+	TBWin
+)
+
+// MaxTablebasePieces is the largest number of pieces, kings included,
+// a table can be generated for.
+const MaxTablebasePieces = 4
+
+// Table entries are plies to mate: positive when the side to move wins,
+// negative (offset by one so mate on the board is -1) when it loses and
+// 0 for draws.
+const (
+	tbIllegal int16 = math.MinInt16
+	// Only used during generation for positions without captures
+	// or promotions
+	tbNoExit int16 = math.MinInt16 + 1
+)
+
+const tbMagic = "CTB1"
+
+// Flags used during generation
+const (
+	tbDone uint8 = 1 << iota
+	tbProcessed
+)
+
+func tbWinValue(plies int) int16 {
+	return int16(plies)
+}
+
+func tbLossValue(plies int) int16 {
+	return int16(-plies - 1)
+}
+
+// tbResult decodes a table entry.
+func tbResult(v int16) TBResult {
+	switch {
+	case v > 0:
+		return TBResult{Outcome: TBWin, DTM: int(v)}
+	case v < 0:
+		return TBResult{Outcome: TBLoss, DTM: int(-v - 1)}
+	default:
+		return TBResult{Outcome: TBDraw}
+	}
+}
+
+// tbScore orders table entries from the point of view of the side to move:
+// quicker wins first, then draws, then slower losses.
+func tbScore(v int16) int {
+	switch {
+	case v == tbNoExit:
+		return math.MinInt32
+	case v > 0:
+		return 1<<20 - int(v)
+	case v < 0:
+		return -(1 << 20) - int(v)
+	default:
+		return 0
+	}
+}
+
+// tbNegate converts an entry for the position after a move into the value
+// of making that move for the side that made it.
+func tbNegate(v int16) int16 {
+	r := tbResult(v)
+	switch r.Outcome {
+	case TBWin:
+		return tbLossValue(r.DTM + 1)
+	case TBLoss:
+		return tbWinValue(r.DTM + 1)
+	default:
+		return 0
+	}
+}
+
+// parseMaterial converts a material string such as "KBNK" into piece indexes,
+// sorted from queen down to pawn within each side.
+func parseMaterial(material string) ([]int, error) {
+	s := strings.ToUpper(material)
+	if !strings.HasPrefix(s, "K") {
+		return nil, fmt.Errorf("invalid material %q: expected white king first", material)
+	}
+
+	split := strings.IndexByte(s[1:], 'K')
+	if split == -1 {
+		return nil, fmt.Errorf("invalid material %q: missing black king", material)
+	}
+
+	pieces := []int{WhiteKing}
+	for color, side := range []string{s[1 : split+1], s[split+2:]} {
+		var sidePieces []int
+		for _, char := range side {
+			idx := strings.IndexRune("PNBRQ", char)
+			if idx == -1 {
+				return nil, fmt.Errorf("invalid material %q: unknown piece %c", material, char)
+			}
+			sidePieces = append(sidePieces, color*6+idx)
+		}
+		slices.Sort(sidePieces)
+		slices.Reverse(sidePieces)
+
+		if color == Black {
+			pieces = append(pieces, BlackKing)
+		}
+		pieces = append(pieces, sidePieces...)
+	}
+
+	if len(pieces) > MaxTablebasePieces {
+		return nil, fmt.Errorf("invalid material %q: at most %d pieces are supported", material, MaxTablebasePieces)
+	}
+
+	return pieces, nil
+}
+
+// materialString is the inverse of parseMaterial.
+func materialString(pieces []int) string {
+	s := strings.Builder{}
+	for _, p := range pieces {
+		s.WriteByte("PNBRQK"[p%6])
+	}
+	return s.String()
+}
+
+// boardMaterial returns the material string of the pieces on the board.
+func boardMaterial(b *Board) string {
+	s := strings.Builder{}
+	for _, offset := range []int{0, 6} {
+		s.WriteByte('K')
+		for i := offset + 4; i >= offset; i-- {
+			s.WriteString(strings.Repeat(string("PNBRQ"[i-offset]), b.Pieces[i].Count()))
+		}
+	}
+	return s.String()
+}
+
+// subMaterials returns every material reachable from pieces by a single
+// capture or promotion.
+func subMaterials(pieces []int) []string {
+	var subs []string
+	add := func(p []int) {
+		// Re-sort the pieces after a promotion
+		sorted, _ := parseMaterial(materialString(p))
+		s := materialString(sorted)
+		if !slices.Contains(subs, s) {
+			subs = append(subs, s)
+		}
+	}
+
+	for i, p := range pieces {
+		if p%6 == WhiteKing {
+			continue
+		}
+		// Capture of piece i
+		add(slices.Delete(slices.Clone(pieces), i, i+1))
+
+		if p%6 != WhitePawn {
+			continue
+		}
+		for promoted := p + 1; promoted <= p+4; promoted++ {
+			next := slices.Clone(pieces)
+			next[i] = promoted
+			add(next)
+
+			// Promotion with a capture of an opposing piece
+			for j, q := range pieces {
+				if q/6 != p/6 && q%6 != WhiteKing {
+					add(slices.Delete(slices.Clone(next), j, j+1))
+				}
+			}
+		}
+	}
+
+	return subs
+}
+
+// mirrorBoard returns the board with colors swapped and ranks flipped, which
+// has the same tablebase value for the side to move.
+func mirrorBoard(b *Board) *Board {
+	m := InitBoard("")
+	for i := range 12 {
+		bb := b.Pieces[i]
+		for bb != 0 {
+			sq := bb.PopLSB() ^ 56
+			m.Pieces[(i+6)%12].Set(sq)
+			m.Occupancy[(i/6)^1].Set(sq)
+		}
+	}
+	m.Occupancy[All] = m.Occupancy[White] | m.Occupancy[Black]
+	m.SideToMove = b.SideToMove ^ 1
+	m.EnPassant = -1
+	if b.EnPassant != -1 {
+		m.EnPassant = b.EnPassant ^ 56
+	}
+	return m
+}
+
+// newTablebase returns an empty table for the pieces.
+func newTablebase(pieces []int) *Tablebase {
+	return &Tablebase{
+		Material: materialString(pieces),
+		pieces:   pieces,
+		values:   make([]int16, 2<<(6*len(pieces))),
+	}
+}
+
+// index packs the side to move and piece squares into a table index.
+func (t *Tablebase) index(side int, squares []int) int {
+	idx := side
+	for _, sq := range squares {
+		idx = idx<<6 | sq
+	}
+	return idx
+}
+
+// squares unpacks a table index into the side to move and piece squares.
+func (t *Tablebase) squares(idx int, squares []int) int {
+	for i := len(t.pieces) - 1; i >= 0; i-- {
+		squares[i] = idx & 0x3F
+		idx >>= 6
+	}
+	return idx
+}
+
+// setup places the pieces for the table index on an empty board and returns
+// false if the index is not a legal position.
+func (t *Tablebase) setup(b *Board, idx int, squares []int) bool {
+	side := t.squares(idx, squares)
+
+	b.Pieces = [12]Bitboard{}
+	b.Occupancy = [3]Bitboard{}
+	b.SideToMove = side
+	b.EnPassant = -1
+	b.CastleRights = 0
+	b.HalfMove = 0
+
+	for i, p := range t.pieces {
+		sq := squares[i]
+		if b.Occupancy[All].Occupied(sq) {
+			return false
+		}
+		// Pawns can never stand on the first or last rank
+		if p%6 == WhitePawn && (sq < 8 || sq > 55) {
+			return false
+		}
+		b.Pieces[p].Set(sq)
+		b.Occupancy[p/6].Set(sq)
+		b.Occupancy[All].Set(sq)
+	}
+
+	// The side that just moved can not be left in check
+	king := b.Pieces[(side^1)*6+5].LSB()
+	return !b.IsSqAttacked(king, side)
+}
+
+// boardIndex returns the table index of the board, assuming its material
+// matches the table.
+func (t *Tablebase) boardIndex(b *Board) int {
+	squares := make([]int, len(t.pieces))
+	var remaining [12]Bitboard
+	copy(remaining[:], b.Pieces[:])
+	for i, p := range t.pieces {
+		squares[i] = remaining[p].PopLSB()
+	}
+	return t.index(b.SideToMove, squares)
+}
+
+// Probe returns the table verdict for the board if its material matches the
+// table and it has no castling rights or en passant square.
+func (t *Tablebase) Probe(b *Board) (TBResult, bool) {
+	v, ok := t.probe(b)
+	if !ok {
+		return TBResult{}, false
+	}
+	return tbResult(v), true
+}
+
+func (t *Tablebase) probe(b *Board) (int16, bool) {
+	if b.CastleRights != 0 || b.EnPassant != -1 || boardMaterial(b) != t.Material {
+		return 0, false
+	}
+	v := t.values[t.boardIndex(b)]
+	return v, v != tbIllegal
+}
+
+// Probe looks up the board in the table for its material, or in the table
+// for the mirrored material with colors swapped.
+func (ts Tablebases) Probe(b *Board) (TBResult, bool) {
+	v, ok := ts.probe(b)
+	if !ok {
+		return TBResult{}, false
+	}
+	return tbResult(v), true
+}
+
+func (ts Tablebases) probe(b *Board) (int16, bool) {
+	if t, ok := ts[boardMaterial(b)]; ok {
+		return t.probe(b)
+	}
+
+	m := mirrorBoard(b)
+	if t, ok := ts[boardMaterial(m)]; ok {
+		return t.probe(m)
+	}
+
+	return 0, false
+}
+
+// has returns true if the material, or its mirror, is in the set.
+func (ts Tablebases) has(material string) bool {
+	if _, ok := ts[material]; ok {
+		return true
+	}
+
+	split := strings.IndexByte(material[1:], 'K') + 1
+	_, ok := ts[material[split:]+material[:split]]
+	return ok
+}
+
+// BestMove returns the legal move that keeps the best tablebase result for
+// the side to move: the fastest mate when winning, any drawing move when
+// drawn and the longest resistance when losing.
+func (ts Tablebases) BestMove(b *Board) (Move, TBResult, bool) {
+	if _, ok := ts.probe(b); !ok {
+		return 0, TBResult{}, false
+	}
+
+	best, bestValue := Move(0), tbNoExit
+	for _, m := range b.GenerateMoves() {
+		undo := b.makeMove(m)
+		v, ok := ts.probe(b)
+		b.unmakeMove(m, undo)
+
+		if !ok {
+			continue
+		}
+		if v = tbNegate(v); tbScore(v) > tbScore(bestValue) {
+			best, bestValue = m, v
+		}
+	}
+
+	if bestValue == tbNoExit {
+		return 0, TBResult{}, false
+	}
+	return best, tbResult(bestValue), true
+}
+
+// Generate builds the table for the material, such as "KQK" or "KBNK", with
+// retrograde analysis and adds it to the set. Tables for positions reachable
+// through captures and promotions are generated first and added as well.
+// Four piece tables take a few minutes and around 200MB of memory.
+func (ts Tablebases) Generate(material string) (*Tablebase, error) {
+	pieces, err := parseMaterial(material)
+	if err != nil {
+		return nil, err
+	}
+
+	key := materialString(pieces)
+	if t, ok := ts[key]; ok {
+		return t, nil
+	}
+
+	for _, sub := range subMaterials(pieces) {
+		if !ts.has(sub) {
+			if _, err := ts.Generate(sub); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	t := newTablebase(pieces)
+	if err := t.generate(ts); err != nil {
+		return nil, err
+	}
+	ts[key] = t
+
+	return t, nil
+}
+
+// generate fills the table. Every legal position is first scored from the
+// moves that leave the table through a capture or promotion, using the
+// smaller tables, and the moves that stay inside it are counted. Mates are
+// then propagated backwards one ply at a time by unmaking moves: a position
+// with a move into a lost position is won, and a position whose moves all
+// lead to won positions is lost. Anything left unresolved is a draw.
+func (t *Tablebase) generate(ts Tablebases) error {
+	size := len(t.values)
+	counters := make([]uint8, size)
+	flags := make([]uint8, size)
+
+	// buckets[d] holds positions whose result is mate in d plies
+	var buckets [][]int32
+	push := func(buckets *[][]int32, plies int, idx int) {
+		for len(*buckets) <= plies {
+			*buckets = append(*buckets, nil)
+		}
+		(*buckets)[plies] = append((*buckets)[plies], int32(idx))
+	}
+
+	workers := runtime.NumCPU()
+	chunk := (size + workers - 1) / workers
+	results := make([][][]int32, workers)
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			b := InitBoard("")
+			squares := make([]int, len(t.pieces))
+			for idx := w * chunk; idx < min(size, (w+1)*chunk); idx++ {
+				if !t.setup(b, idx, squares) {
+					t.values[idx] = tbIllegal
+					flags[idx] = tbDone | tbProcessed
+					continue
+				}
+
+				moves := b.GenerateMoves()
+				count, exit := 0, tbNoExit
+				for _, m := range moves {
+					if !m.IsCapture() && !m.IsPromotion() {
+						count++
+						continue
+					}
+
+					undo := b.makeMove(m)
+					v, ok := ts.probe(b)
+					if !ok {
+						errs[w] = fmt.Errorf("tablebase %s: missing table for %s", t.Material, boardMaterial(b))
+					}
+					b.unmakeMove(m, undo)
+
+					if v = tbNegate(v); tbScore(v) > tbScore(exit) {
+						exit = v
+					}
+				}
+
+				counters[idx] = uint8(count)
+				switch {
+				case len(moves) == 0 && b.IsInCheck():
+					t.values[idx] = tbLossValue(0)
+					flags[idx] = tbDone
+					push(&results[w], 0, idx)
+				case len(moves) == 0:
+					flags[idx] = tbDone | tbProcessed
+				case count == 0:
+					// Only captures and promotions, the result is final
+					t.values[idx] = exit
+					flags[idx] = tbDone
+					if exit != 0 {
+						push(&results[w], tbResult(exit).DTM, idx)
+					}
+				default:
+					// Remember the best exit, a winning one is a candidate
+					// that a quicker mate inside the table may replace
+					t.values[idx] = exit
+					if exit > 0 {
+						push(&results[w], int(exit), idx)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := range workers {
+		if errs[w] != nil {
+			return errs[w]
+		}
+		for plies, bucket := range results[w] {
+			for _, idx := range bucket {
+				push(&buckets, plies, int(idx))
+			}
+		}
+	}
+
+	b := InitBoard("")
+	squares := make([]int, len(t.pieces))
+	preds := make([]int, 0, 64)
+
+	for plies := 0; plies < len(buckets); plies++ {
+		for _, i := range buckets[plies] {
+			idx := int(i)
+			if flags[idx]&tbProcessed != 0 {
+				continue
+			}
+			// Stale candidate replaced by a quicker mate
+			if r := tbResult(t.values[idx]); t.values[idx] == tbNoExit || r.Outcome == TBDraw || r.DTM != plies {
+				continue
+			}
+			flags[idx] |= tbDone | tbProcessed
+
+			t.setup(b, idx, squares)
+			preds = t.predecessors(b, squares, preds[:0])
+
+			for _, pred := range preds {
+				if flags[pred]&tbDone != 0 {
+					continue
+				}
+
+				if t.values[idx] < 0 {
+					// A move into a lost position wins
+					t.values[pred] = tbWinValue(plies + 1)
+					flags[pred] |= tbDone
+					push(&buckets, plies+1, pred)
+					continue
+				}
+
+				counters[pred]--
+				if counters[pred] > 0 {
+					continue
+				}
+
+				// Every move inside the table loses, the position is lost
+				// unless a capture or promotion draws or wins
+				if exit := t.values[pred]; exit == tbNoExit || exit < 0 {
+					loss := plies + 1
+					if exit != tbNoExit {
+						loss = max(loss, tbResult(exit).DTM)
+					}
+					t.values[pred] = tbLossValue(loss)
+					flags[pred] |= tbDone
+					push(&buckets, loss, pred)
+				}
+			}
+		}
+		buckets[plies] = nil
+	}
+
+	for idx := range size {
+		if flags[idx]&tbDone == 0 {
+			t.values[idx] = 0
+		}
+	}
+
+	return nil
+}
+
+// predecessors appends the indexes of positions that reach the board set up
+// from squares with a quiet move by the side that just moved.
+func (t *Tablebase) predecessors(b *Board, squares []int, preds []int) []int {
+	mover := b.SideToMove ^ 1
+	king := b.Pieces[b.SideToMove*6+5].LSB()
+	occ := b.Occupancy[All]
+
+	for i, p := range t.pieces {
+		if p/6 != mover {
+			continue
+		}
+
+		to := squares[i]
+		var from Bitboard
+		switch p % 6 {
+		case WhitePawn:
+			// Pawns step back towards their own side, two squares
+			// if they could have come from the starting rank
+			dir, doubleRank := -8, 3
+			if mover == Black {
+				dir, doubleRank = 8, 4
+			}
+			back := to + dir
+			if back >= 8 && back < 56 && !occ.Occupied(back) {
+				from.Set(back)
+				if to/8 == doubleRank {
+					from.Set(back + dir)
+				}
+			}
+		case WhiteKnight:
+			from = KnightMoves[to]
+		case WhiteBishop:
+			from = BishopAttacks(to, occ)
+		case WhiteRook:
+			from = RookAttacks(to, occ)
+		case WhiteQueen:
+			from = QueenAttacks(to, occ)
+		case WhiteKing:
+			from = KingMoves[to]
+		}
+		from &^= occ
+
+		b.Pieces[p].Clear(to)
+		b.Occupancy[All].Clear(to)
+		for from != 0 {
+			sq := from.PopLSB()
+			b.Pieces[p].Set(sq)
+			b.Occupancy[All].Set(sq)
+			// The side not to move can not be in check
+			if !b.IsSqAttacked(king, mover) {
+				squares[i] = sq
+				preds = append(preds, t.index(mover, squares))
+			}
+			b.Pieces[p].Clear(sq)
+			b.Occupancy[All].Clear(sq)
+		}
+		b.Pieces[p].Set(to)
+		b.Occupancy[All].Set(to)
+		squares[i] = to
+	}
+
+	return preds
+}
+
+// Save writes the table to path. The file is a "CTB1" header, the material
+// string prefixed with its length as one byte, the entry count as a
+// little-endian uint32 and then one little-endian int16 per table index.
+func (t *Tablebase) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	w.WriteString(tbMagic)
+	w.WriteByte(byte(len(t.Material)))
+	w.WriteString(t.Material)
+	binary.Write(w, binary.LittleEndian, uint32(len(t.values)))
+	if err := binary.Write(w, binary.LittleEndian, t.values); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// LoadTablebase reads a table written by Save.
+func LoadTablebase(path string) (*Tablebase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, len(tbMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("tablebase %s: %v", path, err)
+	}
+	if string(header[:len(tbMagic)]) != tbMagic {
+		return nil, fmt.Errorf("tablebase %s: not a tablebase file", path)
+	}
+
+	material := make([]byte, header[len(tbMagic)])
+	if _, err := io.ReadFull(r, material); err != nil {
+		return nil, fmt.Errorf("tablebase %s: %v", path, err)
+	}
+
+	pieces, err := parseMaterial(string(material))
+	if err != nil {
+		return nil, fmt.Errorf("tablebase %s: %v", path, err)
+	}
+	t := newTablebase(pieces)
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("tablebase %s: %v", path, err)
+	}
+	if int(count) != len(t.values) {
+		return nil, fmt.Errorf("tablebase %s: expected %d entries, got %d", path, len(t.values), count)
+	}
+	if err := binary.Read(r, binary.LittleEndian, t.values); err != nil {
+		return nil, fmt.Errorf("tablebase %s: %v", path, err)
+	}
+
+	return t, nil
+}
+
+// SaveDir writes every table in the set to dir as <material>.ctb.
+func (ts Tablebases) SaveDir(dir string) error {
+	for material, t := range ts {
+		if err := t.Save(filepath.Join(dir, material+".ctb")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadDir adds every .ctb table in dir to the set.
+func (ts Tablebases) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.ctb"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		t, err := LoadTablebase(path)
+		if err != nil {
+			return err
+		}
+		ts[t.Material] = t
+	}
+
+	return nil
+}
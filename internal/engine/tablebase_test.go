@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// maxDTM returns the longest win in the table in plies.
+func maxDTM(t *Tablebase) int {
+	longest := 0
+	for _, v := range t.values {
+		if v != tbIllegal && v > 0 {
+			longest = max(longest, int(v))
+		}
+	}
+	return longest
+}
+
+func TestTablebaseGenerate(t *testing.T) {
+	Init("")
+	tables := Tablebases{}
+	for _, tt := range []struct {
+		material string
+		dtm      int
+	}{
+		{"KQK", 19},
+		{"KRK", 31},
+	} {
+		table, err := tables.Generate(tt.material)
+		if err != nil {
+			t.Fatalf("Generate(%s): %v", tt.material, err)
+		}
+		if got := maxDTM(table); got != tt.dtm {
+			t.Errorf("%s: longest mate = %d plies, want %d", tt.material, got, tt.dtm)
+		}
+	}
+
+	tests := []struct {
+		fen     string
+		outcome int
+		dtm     int
+	}{
+		{"6k1/8/6K1/8/8/8/8/R7 w - - 0 1", TBWin, 1},
+		{"R5k1/8/6K1/8/8/8/8/8 b - - 0 1", TBLoss, 0},
+		{"8/8/8/4k3/8/8/8/R3K3 b - - 0 1", TBLoss, 28},
+		// The king takes the undefended rook
+		{"8/8/8/8/8/8/6k1/4K2R b - - 0 1", TBDraw, 0},
+		{"8/8/8/8/8/8/6k1/4K2R w - - 0 1", TBWin, 19},
+	}
+	for _, tt := range tests {
+		b := InitBoard(tt.fen)
+		result, ok := tables.Probe(b)
+		if !ok {
+			t.Errorf("%s: not found", tt.fen)
+			continue
+		}
+		if result.Outcome != tt.outcome || result.DTM != tt.dtm {
+			t.Errorf("%s: Probe = %+v, want outcome %d in %d plies", tt.fen, result, tt.outcome, tt.dtm)
+		}
+	}
+
+	// Colors swapped
+	b := InitBoard("r7/8/8/8/8/6k1/8/6K1 b - - 0 1")
+	if result, ok := tables.Probe(b); !ok || result != (TBResult{TBWin, 1}) {
+		t.Errorf("mirrored Probe = %+v, %v, want a win in 1", result, ok)
+	}
+}
+
+func TestTablebaseBestMove(t *testing.T) {
+	Init("")
+	tables := Tablebases{}
+	if _, err := tables.Generate("KRK"); err != nil {
+		t.Fatal(err)
+	}
+
+	b := InitBoard("6k1/8/6K1/8/8/8/8/R7 w - - 0 1")
+	m, result, ok := tables.BestMove(b)
+	if !ok {
+		t.Fatal("BestMove found no move")
+	}
+	if m.UCI() != "a1a8" || result != (TBResult{TBWin, 1}) {
+		t.Errorf("BestMove = %s %+v, want a1a8 winning in 1", m.UCI(), result)
+	}
+
+	// Following the best moves from both sides mates in the stated plies
+	b = InitBoard("8/8/8/4k3/8/8/8/R3K3 w - - 0 1")
+	start, _ := tables.Probe(b)
+	plies := 0
+	for ; !b.IsCheckmate(); plies++ {
+		m, _, ok := tables.BestMove(b)
+		if !ok || plies > start.DTM {
+			t.Fatalf("no mate after %d plies from a win in %d", plies, start.DTM)
+		}
+		b.makeMove(m)
+	}
+	if plies != start.DTM {
+		t.Errorf("mated after %d plies, want %d", plies, start.DTM)
+	}
+}
+
+func TestTablebaseSaveLoad(t *testing.T) {
+	Init("")
+	tables := Tablebases{}
+	if _, err := tables.Generate("KQK"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := tables.SaveDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadTablebase(filepath.Join(dir, "KQK.ctb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Material != "KQK" || !slices.Equal(loaded.pieces, tables["KQK"].pieces) || !slices.Equal(loaded.values, tables["KQK"].values) {
+		t.Error("loaded KQK table differs from the saved one")
+	}
+
+	all := Tablebases{}
+	if err := all.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(tables) {
+		t.Errorf("LoadDir loaded %d tables, want %d", len(all), len(tables))
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+// Tactical themes found by Themes, named as in the Lichess puzzle database
+const (
+	ThemeFork             = "fork"
+	ThemePin              = "pin"
+	ThemeSkewer           = "skewer"
+	ThemeDiscoveredAttack = "discoveredAttack"
+	ThemeBackRankMate     = "backRankMate"
+	ThemePromotion        = "promotion"
+)
+
+var themeOrder = [...]string{
+	ThemeFork, ThemePin, ThemeSkewer, ThemeDiscoveredAttack, ThemeBackRankMate, ThemePromotion,
+}
+
+// Themes classifies the tactical themes of a solution line. The line starts
+// with a move by the side to move, the solving side, and alternates with the
+// other side's replies. Only the solving side's moves are classified, and
+// the line must be legal from the current position.
+func (b *Board) Themes(line []Move) []string {
+	found := make(map[string]bool)
+	undos := make([]Undo, len(line))
+
+	for i, m := range line {
+		if i%2 == 0 {
+			b.moveThemes(m, found)
+		}
+		undos[i] = b.makeMove(m)
+	}
+
+	// Only a solving move can deliver the mate
+	if len(line)%2 == 1 && b.isBackRankMate() {
+		found[ThemeBackRankMate] = true
+	}
+
+	for i := len(line) - 1; i >= 0; i-- {
+		b.unmakeMove(line[i], undos[i])
+	}
+
+	var themes []string
+	for _, theme := range themeOrder {
+		if found[theme] {
+			themes = append(themes, theme)
+		}
+	}
+	return themes
+}
+
+// moveThemes adds the themes of a single solving move to found, taken from
+// the motifs the moved piece creates.
+func (b *Board) moveThemes(m Move, found map[string]bool) {
+	if m.IsPromotion() {
+		found[ThemePromotion] = true
+	}
+
+	color := b.SideToMove
+	for _, motif := range b.Motifs(m) {
+		if motif.Color != color || motif.Squares[0] != m.To() {
+			continue
+		}
+
+		switch motif.Kind {
+		case Fork:
+			found[ThemeFork] = true
+		case AbsolutePin, RelativePin:
+			found[ThemePin] = true
+		case Skewer:
+			found[ThemeSkewer] = true
+		case DiscoveredAttack, DiscoveredCheck:
+			found[ThemeDiscoveredAttack] = true
+		}
+	}
+}
+
+// isBackRankMate returns true if the side to move is checkmated on its back
+// rank by a rook or queen, with its king boxed in by its own pieces.
+func (b *Board) isBackRankMate() bool {
+	color := b.SideToMove
+	if !b.IsCheckmate() || !b.backRankBoxed(color) {
+		return false
+	}
+
+	rank := Bitboard(0xFF) << (8 * (b.Pieces[color*6+WhiteKing].LSB() / 8))
+	heavy := b.Pieces[(color^1)*6+WhiteRook] | b.Pieces[(color^1)*6+WhiteQueen]
+	checkers := b.checkers(color)
+	return checkers.Count() == 1 && checkers&heavy&rank != 0
+}
@@ -0,0 +1,51 @@
+package engine
+
+// Random keys for Zobrist hashing, one per piece and square, castling rights
+// mask, en passant file and for black to move
+var (
+	ZobristPieces    [12][64]uint64
+	ZobristCastle    [16]uint64
+	ZobristEnPassant [8]uint64
+	ZobristSide      uint64
+)
+
+// InitZobrist fills the Zobrist keys from a fixed seed so keys are the same
+// on every run.
+func InitZobrist() {
+	rng := NewPRNG(1070372)
+
+	for i := range 12 {
+		for sq := range 64 {
+			ZobristPieces[i][sq] = rng.Rand64()
+		}
+	}
+	for i := range 16 {
+		ZobristCastle[i] = rng.Rand64()
+	}
+	for i := range 8 {
+		ZobristEnPassant[i] = rng.Rand64()
+	}
+	ZobristSide = rng.Rand64()
+}
+
+// ZobristKey returns a 64-bit hash of the position: piece placement, side to
+// move, castling rights and en passant file. The move counters are ignored.
+func (b *Board) ZobristKey() uint64 {
+	var key uint64
+	for i := range 12 {
+		pieces := b.Pieces[i]
+		for pieces != 0 {
+			key ^= ZobristPieces[i][pieces.PopLSB()]
+		}
+	}
+
+	key ^= ZobristCastle[b.CastleRights]
+	if b.EnPassant != -1 {
+		key ^= ZobristEnPassant[b.EnPassant%8]
+	}
+	if b.SideToMove == Black {
+		key ^= ZobristSide
+	}
+
+	return key
+}
@@ -0,0 +1,128 @@
+package puzzle
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Glicko-2 system constants. Tau limits how fast the volatility changes,
+// and the deviation is kept within bounds so ratings neither freeze nor
+// reset.
+const (
+	glickoScale   = 173.7178
+	glickoTau     = 0.75
+	glickoEpsilon = 0.000001
+
+	MinDeviation = 45
+	MaxDeviation = 350
+)
+
+// Rating is a Glicko-2 rating on the usual Glicko scale.
+type Rating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+// NewRating returns the rating of a player with no games.
+func NewRating() Rating {
+	return Rating{Rating: 1500, Deviation: MaxDeviation, Volatility: 0.06}
+}
+
+// Update returns the rating after a single game against opponent, with
+// score 1 for a win, 0.5 for a draw and 0 for a loss. Each puzzle attempt
+// is rated as its own rating period.
+func (r Rating) Update(opponent Rating, score float64) Rating {
+	mu := (r.Rating - 1500) / glickoScale
+	phi := r.Deviation / glickoScale
+	muOpponent := (opponent.Rating - 1500) / glickoScale
+	phiOpponent := opponent.Deviation / glickoScale
+
+	g := 1 / math.Sqrt(1+3*phiOpponent*phiOpponent/(math.Pi*math.Pi))
+	expected := 1 / (1 + math.Exp(-g*(mu-muOpponent)))
+	v := 1 / (g * g * expected * (1 - expected))
+	delta := v * g * (score - expected)
+
+	sigma := newVolatility(r.Volatility, phi, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * g * (score - expected)
+
+	return Rating{
+		Rating:     mu*glickoScale + 1500,
+		Deviation:  min(max(phi*glickoScale, MinDeviation), MaxDeviation),
+		Volatility: sigma,
+	}
+}
+
+// newVolatility solves for the new volatility with the Illinois algorithm,
+// step 5 of Glickman's description of Glicko-2.
+func newVolatility(sigma, phi, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+
+	lower := a
+	var upper float64
+	if delta*delta > phi*phi+v {
+		upper = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		upper = a - k*glickoTau
+	}
+
+	fLower, fUpper := f(lower), f(upper)
+	for math.Abs(upper-lower) > glickoEpsilon {
+		c := lower + (lower-upper)*fLower/(fUpper-fLower)
+		fc := f(c)
+		if fc*fUpper <= 0 {
+			lower, fLower = upper, fUpper
+		} else {
+			fLower /= 2
+		}
+		upper, fUpper = c, fc
+	}
+
+	return math.Exp(lower / 2)
+}
+
+// LoadRating reads a rating written by Save, or returns a new rating if
+// the file does not exist yet.
+func LoadRating(path string) (Rating, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewRating(), nil
+	}
+	if err != nil {
+		return Rating{}, err
+	}
+
+	var r Rating
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Rating{}, err
+	}
+	return r, nil
+}
+
+// Save writes the rating to path as JSON, creating its directory.
+func (r Rating) Save(path string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
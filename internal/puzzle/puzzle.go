@@ -0,0 +1,161 @@
+package puzzle
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sp41414/chess/internal/engine"
+)
+
+// DefaultDeviation is the rating deviation given to puzzles from files
+// without a RatingDeviation column.
+const DefaultDeviation = 75
+
+// Puzzle is a position with a forced solution. As in the Lichess puzzle
+// database, FEN is the position before the opponent's move, and Moves starts
+// with that move followed by the solution in UCI notation, alternating with
+// the opponent's replies.
+type Puzzle struct {
+	ID        string
+	FEN       string
+	Moves     []string
+	Rating    int
+	Deviation int
+	// Detected from the solution by Board.Themes rather than read from
+	// the file
+	Themes []string
+
+	// Moves parsed against the positions they are played in
+	line []engine.Move
+}
+
+// LoadCSV reads puzzles from a CSV file, see ReadCSV.
+func LoadCSV(path string) ([]*Puzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadCSV(f)
+}
+
+// ReadCSV reads puzzles in the Lichess puzzle database format. The header
+// row names the columns, of which FEN, Moves and Rating are required and
+// PuzzleId and RatingDeviation are optional; any others, including Themes,
+// are ignored. Every puzzle is checked to be legal, which needs the engine
+// lookup tables to be initialized.
+func ReadCSV(r io.Reader) ([]*Puzzle, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid puzzle file: header: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"fen", "moves", "rating"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("invalid puzzle file: missing %s column", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var puzzles []*Puzzle
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid puzzle file: %v", err)
+		}
+
+		p := &Puzzle{
+			ID:        field(record, "puzzleid"),
+			FEN:       field(record, "fen"),
+			Moves:     strings.Fields(field(record, "moves")),
+			Deviation: DefaultDeviation,
+		}
+		if p.ID == "" {
+			p.ID = strconv.Itoa(row - 1)
+		}
+
+		if p.Rating, err = strconv.Atoi(field(record, "rating")); err != nil {
+			return nil, fmt.Errorf("invalid puzzle on line %d: rating: %v", row, err)
+		}
+		if deviation := field(record, "ratingdeviation"); deviation != "" {
+			if p.Deviation, err = strconv.Atoi(deviation); err != nil {
+				return nil, fmt.Errorf("invalid puzzle on line %d: rating deviation: %v", row, err)
+			}
+		}
+
+		if err := p.parse(); err != nil {
+			return nil, fmt.Errorf("invalid puzzle on line %d: %v", row, err)
+		}
+		puzzles = append(puzzles, p)
+	}
+
+	return puzzles, nil
+}
+
+// parse checks that every move of the puzzle is legal, then detects the
+// themes of the solution.
+func (p *Puzzle) parse() error {
+	if len(p.Moves) < 2 || len(p.Moves)%2 != 0 {
+		return fmt.Errorf("expected the opponent's move and a solution ending on the solver's move, got %d moves", len(p.Moves))
+	}
+
+	b, err := p.board()
+	if err != nil {
+		return err
+	}
+
+	p.line = make([]engine.Move, len(p.Moves))
+	undos := make([]engine.Undo, len(p.Moves))
+	for i, uci := range p.Moves {
+		if p.line[i], err = b.ParseUCI(uci); err != nil {
+			return err
+		}
+		undos[i] = b.PlayMove(p.line[i])
+	}
+
+	for i := len(p.line) - 1; i > 0; i-- {
+		b.UndoMove(p.line[i], undos[i])
+	}
+	p.Themes = b.Themes(p.line[1:])
+
+	return nil
+}
+
+// board returns the puzzle's starting position, before the opponent's move.
+func (p *Puzzle) board() (*engine.Board, error) {
+	b := engine.InitBoard("")
+	if err := b.ParseFEN(p.FEN); err != nil {
+		return nil, err
+	}
+	if err := b.ValidateKings(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// rating returns the puzzle's rating as an opponent in Glicko-2.
+func (p *Puzzle) rating() Rating {
+	return Rating{Rating: float64(p.Rating), Deviation: float64(p.Deviation)}
+}
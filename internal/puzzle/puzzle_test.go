@@ -0,0 +1,18 @@
+package puzzle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sp41414/chess/internal/engine"
+)
+
+func TestReadCSVRejectsPositionWithoutKing(t *testing.T) {
+	engine.Init("")
+	csv := "FEN,Moves,Rating\nk7/8/8/8/8/8/P7/8 w - - 0 1,a2a3 a8b8,1500\n"
+
+	_, err := ReadCSV(strings.NewReader(csv))
+	if err == nil || !strings.Contains(err.Error(), "invalid puzzle on line 2") {
+		t.Errorf("ReadCSV error = %v, want invalid puzzle on line 2", err)
+	}
+}
@@ -0,0 +1,171 @@
+package puzzle
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/sp41414/chess/internal/engine"
+)
+
+// Status of the puzzle in progress
+const (
+	Solving int = iota
+	Solved
+	Failed
+)
+
+var (
+	errNoPuzzle      = errors.New("no puzzle in progress")
+	errNoPuzzlesLeft = errors.New("no puzzles left")
+)
+
+// State is what the player sees of the puzzle in progress.
+type State struct {
+	ID     string
+	Rating int
+	// The current position, with the solver to move while Solving
+	FEN string
+	// The opponent's last move, 0 before the puzzle starts
+	LastMove engine.Move
+	// Solving, Solved or Failed
+	Status int
+	// Themes and solution in UCI, only given once the puzzle is over so
+	// they are no hint
+	Themes   []string
+	Solution []string
+	// The player's rating after the puzzle
+	PlayerRating Rating
+}
+
+// Trainer runs puzzles one at a time and keeps the player's rating. A
+// puzzle is failed by the first wrong move and rated as a game against it.
+type Trainer struct {
+	Rating Rating
+
+	puzzles []*Puzzle
+	played  []bool
+	// Index of the puzzle in progress, -1 before the first
+	current int
+	board   *engine.Board
+	// Index in the puzzle's moves of the next move expected from the player
+	ply      int
+	lastMove engine.Move
+	status   int
+}
+
+// NewTrainer returns a trainer for the puzzles, starting from the player's
+// rating.
+func NewTrainer(puzzles []*Puzzle, rating Rating) *Trainer {
+	return &Trainer{
+		Rating:  rating,
+		puzzles: puzzles,
+		played:  make([]bool, len(puzzles)),
+		current: -1,
+	}
+}
+
+// Next starts the unplayed puzzle rated closest to the player and plays the
+// opponent's first move. A puzzle left unfinished is rated as failed.
+func (t *Trainer) Next() (*State, error) {
+	next := -1
+	for i, p := range t.puzzles {
+		if t.played[i] {
+			continue
+		}
+		distance := math.Abs(float64(p.Rating) - t.Rating.Rating)
+		if next == -1 || distance < math.Abs(float64(t.puzzles[next].Rating)-t.Rating.Rating) {
+			next = i
+		}
+	}
+	if next == -1 {
+		return nil, errNoPuzzlesLeft
+	}
+
+	p := t.puzzles[next]
+	b, err := p.board()
+	if err != nil {
+		return nil, fmt.Errorf("puzzle %s: %v", p.ID, err)
+	}
+
+	if t.current != -1 && t.status == Solving {
+		t.finish(Failed)
+	}
+
+	t.current = next
+	t.played[next] = true
+	t.board = b
+	t.status = Solving
+	t.lastMove = p.line[0]
+	t.board.PlayMove(p.line[0])
+	t.ply = 1
+
+	return t.State(), nil
+}
+
+// Play checks the player's move against the solution. A move other than the
+// one in the solution is still accepted if it mates. While the puzzle goes
+// on the opponent's reply is played straight away.
+func (t *Trainer) Play(m engine.Move) (*State, error) {
+	if t.current == -1 || t.status != Solving {
+		return nil, errNoPuzzle
+	}
+
+	if !slices.Contains(t.board.GenerateMoves(), m) {
+		return nil, fmt.Errorf("illegal move %s", m.UCI())
+	}
+
+	p := t.puzzles[t.current]
+	expected := p.line[t.ply]
+	t.board.PlayMove(m)
+
+	switch {
+	case m != expected && t.board.IsCheckmate():
+		t.finish(Solved)
+	case m != expected:
+		t.finish(Failed)
+	case t.ply == len(p.line)-1:
+		t.finish(Solved)
+	default:
+		t.lastMove = p.line[t.ply+1]
+		t.board.PlayMove(t.lastMove)
+		t.ply += 2
+	}
+
+	return t.State(), nil
+}
+
+// finish ends the puzzle in progress and rates it.
+func (t *Trainer) finish(status int) {
+	t.status = status
+
+	score := 0.0
+	if status == Solved {
+		score = 1
+	}
+	t.Rating = t.Rating.Update(t.puzzles[t.current].rating(), score)
+}
+
+// State returns the puzzle in progress, or nil before the first puzzle.
+func (t *Trainer) State() *State {
+	if t.current == -1 {
+		return nil
+	}
+
+	p := t.puzzles[t.current]
+	state := &State{
+		ID:           p.ID,
+		Rating:       p.Rating,
+		FEN:          t.board.GetFEN(),
+		LastMove:     t.lastMove,
+		Status:       t.status,
+		PlayerRating: t.Rating,
+	}
+	if t.status != Solving {
+		state.Themes = p.Themes
+		state.Solution = p.Moves[1:]
+	}
+
+	return state
+}
@@ -0,0 +1,35 @@
+package puzzle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sp41414/chess/internal/engine"
+)
+
+const trainerCSV = `PuzzleId,FEN,Moves,Rating
+a,6k1/1p3ppp/8/8/8/8/5PPP/R5K1 b - - 0 1,b7b6 a1a8,1500
+b,6k1/1p3ppp/8/8/8/8/5PPP/R5K1 b - - 0 1,b7b5 a1a8,1500
+`
+
+func TestNextFailsUnfinishedPuzzle(t *testing.T) {
+	engine.Init("")
+	puzzles, err := ReadCSV(strings.NewReader(trainerCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := NewRating()
+	trainer := NewTrainer(puzzles, start)
+	if _, err := trainer.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := trainer.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.PlayerRating.Rating >= start.Rating {
+		t.Errorf("rating after skipping a puzzle = %.0f, want below %.0f", state.PlayerRating.Rating, start.Rating)
+	}
+}
@@ -1,13 +1,28 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {engine} from '../models';
+import {puzzle} from '../models';
+
+export function BookMove():Promise<engine.Move>;
+
+export function CheckLegality(arg1:string):Promise<engine.LegalityReport>;
+
+export function ExplainMove(arg1:engine.Move):Promise<string>;
+
+export function GetBookMoves():Promise<Array<engine.BookMove>>;
 
 export function GetFEN():Promise<string>;
 
+export function GetMotifs(arg1:engine.Move):Promise<Array<engine.Motif>>;
+
 export function GetMoves():Promise<Array<engine.Move>>;
 
 export function GetPieces():Promise<Record<number, string>>;
 
+export function GetPuzzleRating():Promise<puzzle.Rating>;
+
+export function GetThreats():Promise<Array<engine.Motif>>;
+
 export function IsCheckmate():Promise<boolean>;
 
 export function IsFiftyMoveRule():Promise<boolean>;
@@ -16,12 +31,34 @@ export function IsInCheck():Promise<boolean>;
 
 export function IsInsufficientMaterial():Promise<boolean>;
 
+export function IsLosingCapture(arg1:engine.Move):Promise<boolean>;
+
 export function IsStalemate():Promise<boolean>;
 
 export function IsThreefoldRepetition():Promise<boolean>;
 
+export function KPKProbe():Promise<number>;
+
+export function LoadBook():Promise<number>;
+
+export function LoadPuzzles():Promise<number>;
+
+export function LoadTablebases():Promise<number>;
+
 export function NewGame():Promise<void>;
 
+export function NextPuzzle():Promise<puzzle.State>;
+
 export function PlayMove(arg1:engine.Move):Promise<engine.Undo>;
 
+export function PlayPuzzleMove(arg1:engine.Move):Promise<puzzle.State>;
+
+export function ProbeTablebase():Promise<engine.TBResult>;
+
+export function SolveMate(arg1:string,arg2:number,arg3:boolean):Promise<engine.MateSolution>;
+
+export function SolveProblem(arg1:string,arg2:string):Promise<engine.ProblemResult>;
+
+export function TablebaseMove():Promise<engine.Move>;
+
 export function UndoMove(arg1:engine.Move,arg2:engine.Undo):Promise<void>;
@@ -2,10 +2,30 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function BookMove() {
+  return window['go']['main']['App']['BookMove']();
+}
+
+export function CheckLegality(arg1) {
+  return window['go']['main']['App']['CheckLegality'](arg1);
+}
+
+export function ExplainMove(arg1) {
+  return window['go']['main']['App']['ExplainMove'](arg1);
+}
+
+export function GetBookMoves() {
+  return window['go']['main']['App']['GetBookMoves']();
+}
+
 export function GetFEN() {
   return window['go']['main']['App']['GetFEN']();
 }
 
+export function GetMotifs(arg1) {
+  return window['go']['main']['App']['GetMotifs'](arg1);
+}
+
 export function GetMoves() {
   return window['go']['main']['App']['GetMoves']();
 }
@@ -14,6 +34,14 @@ export function GetPieces() {
   return window['go']['main']['App']['GetPieces']();
 }
 
+export function GetPuzzleRating() {
+  return window['go']['main']['App']['GetPuzzleRating']();
+}
+
+export function GetThreats() {
+  return window['go']['main']['App']['GetThreats']();
+}
+
 export function IsCheckmate() {
   return window['go']['main']['App']['IsCheckmate']();
 }
@@ -30,6 +58,10 @@ export function IsInsufficientMaterial() {
   return window['go']['main']['App']['IsInsufficientMaterial']();
 }
 
+export function IsLosingCapture(arg1) {
+  return window['go']['main']['App']['IsLosingCapture'](arg1);
+}
+
 export function IsStalemate() {
   return window['go']['main']['App']['IsStalemate']();
 }
@@ -38,14 +70,54 @@ export function IsThreefoldRepetition() {
   return window['go']['main']['App']['IsThreefoldRepetition']();
 }
 
+export function KPKProbe() {
+  return window['go']['main']['App']['KPKProbe']();
+}
+
+export function LoadBook() {
+  return window['go']['main']['App']['LoadBook']();
+}
+
+export function LoadPuzzles() {
+  return window['go']['main']['App']['LoadPuzzles']();
+}
+
+export function LoadTablebases() {
+  return window['go']['main']['App']['LoadTablebases']();
+}
+
 export function NewGame() {
   return window['go']['main']['App']['NewGame']();
 }
 
+export function NextPuzzle() {
+  return window['go']['main']['App']['NextPuzzle']();
+}
+
 export function PlayMove(arg1) {
   return window['go']['main']['App']['PlayMove'](arg1);
 }
 
+export function PlayPuzzleMove(arg1) {
+  return window['go']['main']['App']['PlayPuzzleMove'](arg1);
+}
+
+export function ProbeTablebase() {
+  return window['go']['main']['App']['ProbeTablebase']();
+}
+
+export function SolveMate(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SolveMate'](arg1, arg2, arg3);
+}
+
+export function SolveProblem(arg1, arg2) {
+  return window['go']['main']['App']['SolveProblem'](arg1, arg2);
+}
+
+export function TablebaseMove() {
+  return window['go']['main']['App']['TablebaseMove']();
+}
+
 export function UndoMove(arg1, arg2) {
   return window['go']['main']['App']['UndoMove'](arg1, arg2);
 }
@@ -1,5 +1,203 @@
 export namespace engine {
 	
+	export class BookMove {
+	    Move: number;
+	    Weight: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BookMove(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Move = source["Move"];
+	        this.Weight = source["Weight"];
+	    }
+	}
+	export class LegalityReport {
+	    Status: number;
+	    Issues: string[];
+	    Notes: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LegalityReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Status = source["Status"];
+	        this.Issues = source["Issues"];
+	        this.Notes = source["Notes"];
+	    }
+	}
+	export class MateNode {
+	    Move: number;
+	    SAN: string;
+	    Replies: MateNode[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MateNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Move = source["Move"];
+	        this.SAN = source["SAN"];
+	        this.Replies = this.convertValues(source["Replies"], MateNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MateSolution {
+	    Moves: number;
+	    Key?: MateNode;
+	
+	    static createFrom(source: any = {}) {
+	        return new MateSolution(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Moves = source["Moves"];
+	        this.Key = this.convertValues(source["Key"], MateNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Motif {
+	    Kind: number;
+	    Color: number;
+	    Squares: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Motif(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Kind = source["Kind"];
+	        this.Color = source["Color"];
+	        this.Squares = source["Squares"];
+	    }
+	}
+	export class ProblemNode {
+	    Move: number;
+	    SAN: string;
+	    Replies: ProblemNode[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProblemNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Move = source["Move"];
+	        this.SAN = source["SAN"];
+	        this.Replies = this.convertValues(source["Replies"], ProblemNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProblemResult {
+	    Stipulation: string;
+	    Solutions: ProblemNode[];
+	    Cooked: boolean;
+	    Duals: string[];
+	    Notation: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProblemResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Stipulation = source["Stipulation"];
+	        this.Solutions = this.convertValues(source["Solutions"], ProblemNode);
+	        this.Cooked = source["Cooked"];
+	        this.Duals = source["Duals"];
+	        this.Notation = source["Notation"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TBResult {
+	    Outcome: number;
+	    DTM: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TBResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Outcome = source["Outcome"];
+	        this.DTM = source["DTM"];
+	    }
+	}
 	export class Undo {
 	    Captured: number;
 	    CastleRights: number;
@@ -21,3 +219,68 @@ export namespace engine {
 
 }
 
+export namespace puzzle {
+	
+	export class Rating {
+	    Rating: number;
+	    Deviation: number;
+	    Volatility: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Rating(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Rating = source["Rating"];
+	        this.Deviation = source["Deviation"];
+	        this.Volatility = source["Volatility"];
+	    }
+	}
+	export class State {
+	    ID: string;
+	    Rating: number;
+	    FEN: string;
+	    LastMove: number;
+	    Status: number;
+	    Themes: string[];
+	    Solution: string[];
+	    PlayerRating: Rating;
+	
+	    static createFrom(source: any = {}) {
+	        return new State(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ID = source["ID"];
+	        this.Rating = source["Rating"];
+	        this.FEN = source["FEN"];
+	        this.LastMove = source["LastMove"];
+	        this.Status = source["Status"];
+	        this.Themes = source["Themes"];
+	        this.Solution = source["Solution"];
+	        this.PlayerRating = this.convertValues(source["PlayerRating"], Rating);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
@@ -3,16 +3,23 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 
 	"github.com/sp41414/chess/internal/engine"
+	"github.com/sp41414/chess/internal/puzzle"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type App struct {
-	ctx   context.Context
-	board *engine.Board
+	ctx     context.Context
+	session *session
 }
 
 //go:embed all:internal/ui/dist
@@ -20,7 +27,7 @@ var assets embed.FS
 
 func NewApp() *App {
 	return &App{
-		board: engine.Init("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"),
+		session: newSession(),
 	}
 }
 
@@ -28,62 +35,392 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 }
 
-// wrappers for wails bindings from go to the frontend
+// wrappers for wails bindings from go to the frontend,
+// every call goes through the session lock
 
 func (a *App) NewGame() {
-	a.board = engine.Init("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	a.session.reset()
 }
 
 func (a *App) GetFEN() string {
-	return a.board.GetFEN()
+	return withBoard(a.session, (*engine.Board).GetFEN)
 }
 
 func (a *App) GetMoves() []engine.Move {
-	return a.board.GetMoves()
+	return withBoard(a.session, (*engine.Board).GetMoves)
 }
 
 func (a *App) IsInCheck() bool {
-	return a.board.IsInCheck()
+	return withBoard(a.session, (*engine.Board).IsInCheck)
 }
 
 func (a *App) IsCheckmate() bool {
-	return a.board.IsCheckmate()
+	return withBoard(a.session, (*engine.Board).IsCheckmate)
 }
 
 func (a *App) IsStalemate() bool {
-	return a.board.IsStalemate()
+	return withBoard(a.session, (*engine.Board).IsStalemate)
 }
 
 func (a *App) IsFiftyMoveRule() bool {
-	return a.board.IsFiftyMoveRule()
+	return withBoard(a.session, (*engine.Board).IsFiftyMoveRule)
 }
 
 func (a *App) IsInsufficientMaterial() bool {
-	return a.board.IsInsufficientMaterial()
+	return withBoard(a.session, (*engine.Board).IsInsufficientMaterial)
 }
 
 func (a *App) IsThreefoldRepetition() bool {
-	return a.board.IsThreefoldRepetition()
+	return withBoard(a.session, (*engine.Board).IsThreefoldRepetition)
 }
 
 // Unused
 // func (a *App) IsDraw() bool {
-// 	return a.board.IsDraw()
+// 	return withBoard(a.session, (*engine.Board).IsDraw)
 // }
 
 func (a *App) PlayMove(m engine.Move) engine.Undo {
-	return a.board.PlayMove(m)
+	return withBoard(a.session, func(b *engine.Board) engine.Undo {
+		return b.PlayMove(m)
+	})
 }
 
 func (a *App) UndoMove(m engine.Move, u engine.Undo) {
-	a.board.UndoMove(m, u)
+	a.session.do(func(b *engine.Board) {
+		b.UndoMove(m, u)
+	})
 }
 
 func (a *App) GetPieces() map[int]string {
-	return a.board.GetPieces()
+	return withBoard(a.session, (*engine.Board).GetPieces)
+}
+
+func (a *App) IsLosingCapture(m engine.Move) (bool, error) {
+	return withLegalMove(a.session, m, func(b *engine.Board) bool {
+		return b.IsLosingCapture(m)
+	})
+}
+
+// GetThreats returns the tactical motifs in the current position for both
+// sides, for the threats overlay.
+func (a *App) GetThreats() []engine.Motif {
+	return withBoard(a.session, (*engine.Board).Threats)
+}
+
+// GetMotifs returns the tactical motifs the move would create.
+func (a *App) GetMotifs(m engine.Move) ([]engine.Motif, error) {
+	return withLegalMove(a.session, m, func(b *engine.Board) []engine.Motif {
+		return b.Motifs(m)
+	})
+}
+
+// ExplainMove describes the move in the current position in plain English
+// for beginners.
+func (a *App) ExplainMove(m engine.Move) (string, error) {
+	return withLegalMove(a.session, m, func(b *engine.Board) string {
+		return b.Explain(m)
+	})
+}
+
+// KPKProbe returns the exact king and pawn versus king result for the side
+// to move: 1 for a win, 0 for a draw and -1 for a loss.
+func (a *App) KPKProbe() (int, error) {
+	var result int
+	var ok bool
+	a.session.do(func(b *engine.Board) {
+		result, ok = engine.KPKProbe(b)
+	})
+
+	if !ok {
+		return 0, errors.New("position is not king and pawn versus king")
+	}
+	return result, nil
+}
+
+// SolveMate looks for the shortest forced mate in at most n moves in the
+// given position, returning nil if there is none.
+func (a *App) SolveMate(fen string, n int, checksOnly bool) (*engine.MateSolution, error) {
+	b := engine.InitBoard("")
+	if err := b.ParseFEN(fen); err != nil {
+		return nil, err
+	}
+	if err := b.ValidateKings(); err != nil {
+		return nil, err
+	}
+
+	return b.SolveMate(n, checksOnly), nil
+}
+
+// SolveProblem finds every solution of a chess problem with the given
+// stipulation, such as "h#2", "s#3" or "=2", in the position.
+func (a *App) SolveProblem(fen, stipulation string) (*engine.ProblemResult, error) {
+	st, err := engine.ParseStipulation(stipulation)
+	if err != nil {
+		return nil, err
+	}
+
+	b := engine.InitBoard("")
+	if err := b.ParseFEN(fen); err != nil {
+		return nil, err
+	}
+	if err := b.ValidateKings(); err != nil {
+		return nil, err
+	}
+
+	return b.SolveProblem(st), nil
+}
+
+// CheckLegality reports whether the position can arise in a game, labelled
+// engine.Legal, engine.Illegal or engine.Undetermined.
+func (a *App) CheckLegality(fen string) (*engine.LegalityReport, error) {
+	b := engine.InitBoard("")
+	if err := b.ParseFEN(fen); err != nil {
+		return nil, err
+	}
+
+	return b.CheckLegality(), nil
+}
+
+// tablebaseDir returns where endgame tables are kept by default, which is
+// also where "chess tb gen" writes them.
+func tablebaseDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chess", "tablebases"), nil
+}
+
+// loadTablebases loads every table in dir, or in tablebaseDir if dir is
+// empty.
+func loadTablebases(dir string) (engine.Tablebases, error) {
+	if dir == "" {
+		var err error
+		if dir, err = tablebaseDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	ts := engine.Tablebases{}
+	if err := ts.LoadDir(dir); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// LoadTablebases asks for a directory of .ctb endgame tables, as written by
+// "chess tb gen", and probes them from then on. Returns the number of tables
+// loaded, or 0 if no directory was chosen.
+func (a *App) LoadTablebases() (int, error) {
+	dir, err := runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Open Tablebases",
+	})
+	if err != nil || dir == "" {
+		return 0, err
+	}
+
+	ts, err := loadTablebases(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	a.session.setTablebases(ts)
+	return len(ts), nil
+}
+
+// ProbeTablebase returns the exact result and distance to mate of the
+// current position from the loaded endgame tables.
+func (a *App) ProbeTablebase() (engine.TBResult, error) {
+	return withTablebases(a.session, func(b *engine.Board, ts engine.Tablebases) (engine.TBResult, error) {
+		result, ok := ts.Probe(b)
+		if !ok {
+			return engine.TBResult{}, errNoTablebase
+		}
+		return result, nil
+	})
+}
+
+// TablebaseMove returns the move that keeps the best endgame table result:
+// the fastest mate when winning and the longest resistance when losing.
+func (a *App) TablebaseMove() (engine.Move, error) {
+	return withTablebases(a.session, func(b *engine.Board, ts engine.Tablebases) (engine.Move, error) {
+		m, _, ok := ts.BestMove(b)
+		if !ok {
+			return 0, errNoTablebase
+		}
+		return m, nil
+	})
+}
+
+// bookPath returns where the opening book is kept by default, which is also
+// where "chess book build" writes it.
+func bookPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chess", "book.bin"), nil
+}
+
+// loadBook reads the Polyglot book at path, or at bookPath if path is
+// empty. A missing default book is not an error and returns nil.
+func loadBook(path string) (*engine.Book, error) {
+	if path != "" {
+		return engine.LoadBook(path)
+	}
+
+	path, err := bookPath()
+	if err != nil {
+		return nil, err
+	}
+	bk, err := engine.LoadBook(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return bk, err
+}
+
+// LoadBook asks for a Polyglot .bin opening book and uses it from then on.
+// Returns the number of book entries, or 0 if no file was chosen.
+func (a *App) LoadBook() (int, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "Open Opening Book",
+		Filters: []runtime.FileFilter{{DisplayName: "Polyglot Books (*.bin)", Pattern: "*.bin"}},
+	})
+	if err != nil || path == "" {
+		return 0, err
+	}
+
+	bk, err := loadBook(path)
+	if err != nil {
+		return 0, err
+	}
+
+	a.session.setBook(bk)
+	return bk.Len(), nil
+}
+
+// GetBookMoves returns the opening book moves for the current position with
+// their weights, highest first. Empty once the game has left the book.
+func (a *App) GetBookMoves() ([]engine.BookMove, error) {
+	return withBook(a.session, func(b *engine.Board, bk *engine.Book) ([]engine.BookMove, error) {
+		return bk.Moves(b), nil
+	})
+}
+
+// BookMove picks an opening book move for the current position at random,
+// in proportion to the book weights, for the computer's opening moves.
+func (a *App) BookMove() (engine.Move, error) {
+	return withBook(a.session, func(b *engine.Board, bk *engine.Book) (engine.Move, error) {
+		m, ok := bk.RandomMove(b)
+		if !ok {
+			return 0, errOutOfBook
+		}
+		return m, nil
+	})
+}
+
+// puzzleRatingPath returns where the puzzle rating is kept between runs.
+func puzzleRatingPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chess", "puzzle-rating.json"), nil
+}
+
+// LoadPuzzles asks for a puzzle CSV file and starts puzzle mode with it.
+// Returns the number of puzzles loaded, or 0 if no file was chosen.
+func (a *App) LoadPuzzles() (int, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "Open Puzzles",
+		Filters: []runtime.FileFilter{{DisplayName: "Puzzles (*.csv)", Pattern: "*.csv"}},
+	})
+	if err != nil || path == "" {
+		return 0, err
+	}
+
+	puzzles, err := puzzle.LoadCSV(path)
+	if err != nil {
+		return 0, err
+	}
+
+	rating, err := a.GetPuzzleRating()
+	if err != nil {
+		return 0, err
+	}
+
+	a.session.setTrainer(puzzle.NewTrainer(puzzles, rating))
+	return len(puzzles), nil
+}
+
+// savePuzzleRating keeps the player's rating from the puzzle state between
+// runs.
+func savePuzzleRating(state *puzzle.State) error {
+	path, err := puzzleRatingPath()
+	if err != nil {
+		return err
+	}
+	return state.PlayerRating.Save(path)
+}
+
+// NextPuzzle starts the unplayed puzzle rated closest to the player. Skipping
+// a puzzle in progress fails it, so the rating is saved as well.
+func (a *App) NextPuzzle() (*puzzle.State, error) {
+	state, err := withTrainer(a.session, (*puzzle.Trainer).Next)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := savePuzzleRating(state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// PlayPuzzleMove plays the player's move in the puzzle along with the
+// opponent's reply, saving the rating once the puzzle is over.
+func (a *App) PlayPuzzleMove(m engine.Move) (*puzzle.State, error) {
+	state, err := withTrainer(a.session, func(t *puzzle.Trainer) (*puzzle.State, error) {
+		return t.Play(m)
+	})
+	if err != nil || state.Status == puzzle.Solving {
+		return state, err
+	}
+
+	if err := savePuzzleRating(state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// GetPuzzleRating returns the player's puzzle rating.
+func (a *App) GetPuzzleRating() (puzzle.Rating, error) {
+	rating, err := withTrainer(a.session, func(t *puzzle.Trainer) (puzzle.Rating, error) {
+		return t.Rating, nil
+	})
+	if !errors.Is(err, errNoPuzzles) {
+		return rating, err
+	}
+
+	path, err := puzzleRatingPath()
+	if err != nil {
+		return puzzle.Rating{}, err
+	}
+	return puzzle.LoadRating(path)
 }
 
 func main() {
+	if ok, err := runCommand(os.Args[1:]); ok {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	app := NewApp()
 
 	err := wails.Run(&options.App{
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sp41414/chess/internal/engine"
+)
+
+// Positions from the frontend are not trusted: a side without a king used
+// to crash move generation.
+func TestFENBindingsRejectInvalidPositions(t *testing.T) {
+	app := NewApp()
+	fens := []string{
+		"8/8/8/8/8/8/8/K7 w - - 0 1",
+		"k7/8/8/8/8/8/8/8 w - - 0 1",
+		"k7/8/8/8/8/8/8/KK6 w - - 0 1",
+	}
+
+	bindings := map[string]func(fen string) error{
+		"SolveMate": func(fen string) error {
+			_, err := app.SolveMate(fen, 2, false)
+			return err
+		},
+		"SolveProblem": func(fen string) error {
+			_, err := app.SolveProblem(fen, "#2")
+			return err
+		},
+	}
+
+	for name, binding := range bindings {
+		for _, fen := range fens {
+			if err := binding(fen); err == nil {
+				t.Errorf("%s(%q) returned no error", name, fen)
+			}
+		}
+	}
+}
+
+// Moves from the frontend are not trusted: a move from an empty square used
+// to index the piece tables with -1.
+func TestMoveBindingsRejectIllegalMoves(t *testing.T) {
+	app := NewApp()
+	moves := []engine.Move{
+		// From the empty e4 square
+		engine.NewMove(28, 36, engine.QuietMove),
+		engine.NewMove(28, 52, engine.Capture),
+		// Legal squares but not a capture in the start position
+		engine.NewMove(12, 28, engine.Capture),
+	}
+
+	bindings := map[string]func(m engine.Move) error{
+		"GetMotifs": func(m engine.Move) error {
+			_, err := app.GetMotifs(m)
+			return err
+		},
+		"ExplainMove": func(m engine.Move) error {
+			_, err := app.ExplainMove(m)
+			return err
+		},
+		"IsLosingCapture": func(m engine.Move) error {
+			_, err := app.IsLosingCapture(m)
+			return err
+		},
+	}
+
+	for name, binding := range bindings {
+		for _, m := range moves {
+			if err := binding(m); err == nil {
+				t.Errorf("%s(%s) returned no error", name, m.UCI())
+			}
+		}
+	}
+}
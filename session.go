@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/sp41414/chess/internal/engine"
+	"github.com/sp41414/chess/internal/puzzle"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var (
+	errNoPuzzles   = errors.New("no puzzles loaded")
+	errNoTablebase = errors.New("no tablebase loaded for the position")
+	errNoBook      = errors.New("no opening book loaded")
+	errOutOfBook   = errors.New("position is not in the opening book")
+)
+
+// session owns the game state behind the App bindings. Wails invokes bound
+// methods concurrently, so the board is only ever touched while holding mu.
+// Queries take the same exclusive lock as moves because move generation and
+// the terminal state checks make and unmake moves on the board internally.
+type session struct {
+	mu    sync.Mutex
+	board *engine.Board
+	// Puzzle mode, nil until puzzles are loaded
+	trainer *puzzle.Trainer
+	// Endgame tables, nil until first probed or loaded
+	tablebases engine.Tablebases
+	// Opening book, nil until first used or loaded
+	book *engine.Book
+}
+
+// newSession returns a session with the starting position, initializing the
+// engine lookup tables on the way.
+func newSession() *session {
+	return &session{
+		board: engine.Init(startFEN),
+	}
+}
+
+// do runs fn with exclusive access to the board.
+func (s *session) do(fn func(b *engine.Board)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.board)
+}
+
+// reset replaces the board with the starting position. The lookup tables
+// are already built, so only the board itself is recreated.
+func (s *session) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.board = engine.InitBoard(startFEN)
+}
+
+// withBoard runs fn with exclusive access to the session board and returns
+// its result.
+func withBoard[T any](s *session, fn func(b *engine.Board) T) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.board)
+}
+
+// withLegalMove runs fn with exclusive access to the board and returns its
+// result, or an error if the move is not legal in the current position.
+// Moves from the frontend are checked this way before they are made or
+// evaluated, as an illegal move can index an empty square's piece.
+func withLegalMove[T any](s *session, m engine.Move, fn func(b *engine.Board) T) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(s.board.GenerateMoves(), m) {
+		var zero T
+		return zero, fmt.Errorf("illegal move %s", m.UCI())
+	}
+	return fn(s.board), nil
+}
+
+// setTrainer starts puzzle mode with the trainer, replacing any puzzles
+// loaded before.
+func (s *session) setTrainer(t *puzzle.Trainer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trainer = t
+}
+
+// withTrainer runs fn with exclusive access to the puzzle trainer and returns
+// its result, or errNoPuzzles if no puzzles are loaded.
+func withTrainer[T any](s *session, fn func(t *puzzle.Trainer) (T, error)) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.trainer == nil {
+		var zero T
+		return zero, errNoPuzzles
+	}
+	return fn(s.trainer)
+}
+
+// setTablebases replaces the endgame tables used for probing.
+func (s *session) setTablebases(ts engine.Tablebases) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tablebases = ts
+}
+
+// withTablebases runs fn with exclusive access to the board and the endgame
+// tables and returns its result. If no tables were loaded before, the ones
+// in tablebaseDir are read without holding the lock, since a four piece
+// table is 64MB, and only kept if there are any so tables generated later
+// are still picked up.
+func withTablebases[T any](s *session, fn func(b *engine.Board, ts engine.Tablebases) (T, error)) (T, error) {
+	s.mu.Lock()
+	loaded := s.tablebases != nil
+	s.mu.Unlock()
+
+	var ts engine.Tablebases
+	if !loaded {
+		var err error
+		if ts, err = loadTablebases(""); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tablebases == nil && len(ts) > 0 {
+		s.tablebases = ts
+	}
+	if s.tablebases != nil {
+		ts = s.tablebases
+	}
+	return fn(s.board, ts)
+}
+
+// setBook replaces the opening book.
+func (s *session) setBook(bk *engine.Book) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.book = bk
+}
+
+// withBook runs fn with exclusive access to the board and the opening book
+// and returns its result. If no book was loaded before, the one at bookPath
+// is read without holding the lock. Returns errNoBook if there is none.
+func withBook[T any](s *session, fn func(b *engine.Board, bk *engine.Book) (T, error)) (T, error) {
+	s.mu.Lock()
+	loaded := s.book != nil
+	s.mu.Unlock()
+
+	var bk *engine.Book
+	if !loaded {
+		var err error
+		if bk, err = loadBook(""); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.book == nil {
+		s.book = bk
+	}
+	if s.book == nil {
+		var zero T
+		return zero, errNoBook
+	}
+	return fn(s.board, s.book)
+}
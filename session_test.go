@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sp41414/chess/internal/engine"
+)
+
+// Run with -race: the bindings are called from several goroutines at once,
+// as Wails does. Moves are only played in pairs with their undo, and seq
+// keeps those pairs and NewGame from interleaving with each other so the
+// board always holds a real position, while the queries run unsynchronized.
+func TestConcurrentBindings(t *testing.T) {
+	app := NewApp()
+
+	var seq sync.Mutex
+	var wg sync.WaitGroup
+	const iterations = 200
+
+	for range 2 {
+		wg.Go(func() {
+			for range iterations {
+				seq.Lock()
+				moves := app.GetMoves()
+				u := app.PlayMove(moves[0])
+				app.UndoMove(moves[0], u)
+				seq.Unlock()
+			}
+		})
+	}
+
+	wg.Go(func() {
+		for range iterations {
+			seq.Lock()
+			app.NewGame()
+			seq.Unlock()
+		}
+	})
+
+	for range 4 {
+		wg.Go(func() {
+			for range iterations {
+				if app.GetFEN() == "" {
+					t.Error("GetFEN returned an empty FEN")
+				}
+				if len(app.GetMoves()) == 0 {
+					t.Error("GetMoves returned no moves")
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if fen := app.GetFEN(); fen != startFEN {
+		t.Errorf("GetFEN() = %q, want %q", fen, startFEN)
+	}
+}
+
+// Tables generated after a probe found none are still picked up.
+func TestProbeTablebaseLoadsLaterTables(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+	app.session.board = engine.InitBoard("6k1/8/6K1/8/8/8/8/R7 w - - 0 1")
+
+	if _, err := app.ProbeTablebase(); !errors.Is(err, errNoTablebase) {
+		t.Fatalf("ProbeTablebase with no tables: error = %v, want %v", err, errNoTablebase)
+	}
+
+	dir, err := tablebaseDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tables := engine.Tablebases{}
+	if _, err := tables.Generate("KRK"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tables.SaveDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := app.ProbeTablebase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (engine.TBResult{Outcome: engine.TBWin, DTM: 1}) {
+		t.Errorf("ProbeTablebase = %+v, want a win in 1", result)
+	}
+}
+
+func TestBookMovesLoadDefaultBook(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	if _, err := app.GetBookMoves(); !errors.Is(err, errNoBook) {
+		t.Fatalf("GetBookMoves with no book: error = %v, want %v", err, errNoBook)
+	}
+
+	// One entry for 1.e4 in the starting position: key, move e2e4, weight
+	// and learn, all big-endian
+	var entry []byte
+	entry = binary.BigEndian.AppendUint64(entry, engine.PolyglotKey(app.session.board))
+	entry = binary.BigEndian.AppendUint16(entry, 12<<6|28)
+	entry = binary.BigEndian.AppendUint16(entry, 1)
+	entry = binary.BigEndian.AppendUint32(entry, 0)
+
+	path, err := bookPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, entry, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	moves, err := app.GetBookMoves()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[0].Move.UCI() != "e2e4" || moves[0].Weight != 1 {
+		t.Errorf("GetBookMoves = %v, want e2e4 with weight 1", moves)
+	}
+
+	m, err := app.BookMove()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.PlayMove(m)
+	if _, err := app.BookMove(); !errors.Is(err, errOutOfBook) {
+		t.Errorf("BookMove after 1.e4: error = %v, want %v", err, errOutOfBook)
+	}
+}